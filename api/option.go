@@ -0,0 +1,237 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Option types
+const (
+	OptionString  = "string"
+	OptionInteger = "int"
+	OptionBool    = "bool"
+	OptionEnum    = "enum"
+	OptionDict    = "dict"
+)
+
+// Option is a typed POC option. POCs declare their options by returning
+// them from GetOptions, keyed by name; values set from the console or the
+// command line are validated and converted before the POC runs.
+type Option interface {
+	Type() string
+	Description() string
+	Default() interface{}
+	IsRequired() bool
+	// Convert checks a user supplied value and converts it to the option's
+	// type.
+	Convert(value interface{}) (interface{}, error)
+}
+
+// OptString is a free-form string option
+type OptString struct {
+	Value    string
+	Desc     string
+	Required bool
+}
+
+// NewOptString creates a string option
+func NewOptString(value, desc string, required bool) *OptString {
+	return &OptString{Value: value, Desc: desc, Required: required}
+}
+
+func (o *OptString) Type() string         { return OptionString }
+func (o *OptString) Description() string  { return o.Desc }
+func (o *OptString) Default() interface{} { return o.Value }
+func (o *OptString) IsRequired() bool     { return o.Required }
+
+func (o *OptString) Convert(value interface{}) (interface{}, error) {
+	return fmt.Sprintf("%v", value), nil
+}
+
+// OptInteger is an integer option
+type OptInteger struct {
+	Value    int
+	Desc     string
+	Required bool
+}
+
+// NewOptInteger creates an integer option
+func NewOptInteger(value int, desc string, required bool) *OptInteger {
+	return &OptInteger{Value: value, Desc: desc, Required: required}
+}
+
+func (o *OptInteger) Type() string         { return OptionInteger }
+func (o *OptInteger) Description() string  { return o.Desc }
+func (o *OptInteger) Default() interface{} { return o.Value }
+func (o *OptInteger) IsRequired() bool     { return o.Required }
+
+func (o *OptInteger) Convert(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v != float64(int(v)) {
+			return nil, fmt.Errorf("%v is not an integer", v)
+		}
+		return int(v), nil
+	default:
+		n, err := strconv.Atoi(strings.TrimSpace(fmt.Sprintf("%v", value)))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", value)
+		}
+		return n, nil
+	}
+}
+
+// OptBool is a boolean option
+type OptBool struct {
+	Value    bool
+	Desc     string
+	Required bool
+}
+
+// NewOptBool creates a boolean option
+func NewOptBool(value bool, desc string, required bool) *OptBool {
+	return &OptBool{Value: value, Desc: desc, Required: required}
+}
+
+func (o *OptBool) Type() string         { return OptionBool }
+func (o *OptBool) Description() string  { return o.Desc }
+func (o *OptBool) Default() interface{} { return o.Value }
+func (o *OptBool) IsRequired() bool     { return o.Required }
+
+func (o *OptBool) Convert(value interface{}) (interface{}, error) {
+	if b, ok := value.(bool); ok {
+		return b, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(fmt.Sprintf("%v", value))) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0":
+		return false, nil
+	default:
+		return nil, fmt.Errorf("%q is not a boolean", value)
+	}
+}
+
+// OptEnum is a string option restricted to a set of choices
+type OptEnum struct {
+	Value    string
+	Choices  []string
+	Desc     string
+	Required bool
+}
+
+// NewOptEnum creates an enum option
+func NewOptEnum(value string, choices []string, desc string, required bool) *OptEnum {
+	return &OptEnum{Value: value, Choices: choices, Desc: desc, Required: required}
+}
+
+func (o *OptEnum) Type() string         { return OptionEnum }
+func (o *OptEnum) Description() string  { return o.Desc }
+func (o *OptEnum) Default() interface{} { return o.Value }
+func (o *OptEnum) IsRequired() bool     { return o.Required }
+
+func (o *OptEnum) Convert(value interface{}) (interface{}, error) {
+	s := fmt.Sprintf("%v", value)
+	for _, choice := range o.Choices {
+		if s == choice {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not one of %s", s, strings.Join(o.Choices, ", "))
+}
+
+// OptDict lets the user pick one of a set of named values, such as payload
+// templates; the option resolves to the value of the chosen name.
+type OptDict struct {
+	Value    string
+	Choices  map[string]string
+	Desc     string
+	Required bool
+}
+
+// NewOptDict creates a dict option
+func NewOptDict(value string, choices map[string]string, desc string, required bool) *OptDict {
+	return &OptDict{Value: value, Choices: choices, Desc: desc, Required: required}
+}
+
+func (o *OptDict) Type() string         { return OptionDict }
+func (o *OptDict) Description() string  { return o.Desc }
+func (o *OptDict) Default() interface{} { return o.Value }
+func (o *OptDict) IsRequired() bool     { return o.Required }
+
+func (o *OptDict) Convert(value interface{}) (interface{}, error) {
+	key := fmt.Sprintf("%v", value)
+	if v, ok := o.Choices[key]; ok {
+		return v, nil
+	}
+
+	keys := make([]string, 0, len(o.Choices))
+	for k := range o.Choices {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return nil, fmt.Errorf("%q is not one of %s", key, strings.Join(keys, ", "))
+}
+
+// ResolveOptions validates values against the declared options of a POC.
+// Required options must be set in values, whatever their default, since
+// the default of an integer or boolean option cannot tell "not given" from
+// 0 or false. Other declared options that were not set fall back to their
+// default, and every declared value is converted to its type. Values that
+// do not belong to a declared option are passed through.
+func ResolveOptions(declared map[string]interface{}, values map[string]interface{}) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{}, len(values)+len(declared))
+	for k, v := range values {
+		resolved[k] = v
+	}
+
+	names := make([]string, 0, len(declared))
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		opt, ok := declared[name].(Option)
+		if !ok {
+			continue
+		}
+
+		value, set := values[name]
+		if !set || isZero(value) {
+			if opt.IsRequired() {
+				return nil, fmt.Errorf("option '%s' is required", name)
+			}
+			if isZero(opt.Default()) {
+				continue
+			}
+			value = opt.Default()
+		}
+
+		converted, err := opt.Convert(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for option '%s': %w", name, err)
+		}
+		resolved[name] = converted
+	}
+
+	return resolved, nil
+}
+
+func isZero(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	default:
+		return false
+	}
+}
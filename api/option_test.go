@@ -0,0 +1,140 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOptionConvert(t *testing.T) {
+	dict := NewOptDict("probe", map[string]string{"probe": "check", "exploit": "run"}, "", false)
+	enum := NewOptEnum("fast", []string{"fast", "slow"}, "", false)
+
+	tests := []struct {
+		name    string
+		opt     Option
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{"string", NewOptString("", "", false), 42, "42", false},
+		{"int from string", NewOptInteger(0, "", false), " 8080 ", 8080, false},
+		{"int from int64", NewOptInteger(0, "", false), int64(7), 7, false},
+		{"int from whole float", NewOptInteger(0, "", false), 3.0, 3, false},
+		{"int from fraction", NewOptInteger(0, "", false), 3.5, nil, true},
+		{"int from text", NewOptInteger(0, "", false), "many", nil, true},
+		{"bool", NewOptBool(false, "", false), true, true, false},
+		{"bool from yes", NewOptBool(false, "", false), "Yes", true, false},
+		{"bool from 0", NewOptBool(true, "", false), "0", false, false},
+		{"bool from text", NewOptBool(false, "", false), "maybe", nil, true},
+		{"enum choice", enum, "slow", "slow", false},
+		{"enum other", enum, "medium", nil, true},
+		{"dict name", dict, "exploit", "run", false},
+		{"dict value", dict, "run", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.opt.Convert(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Convert(%v) error = %v, wantErr %v", tt.name, tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Convert(%v) = %#v, want %#v", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestOptionTypes(t *testing.T) {
+	tests := []struct {
+		opt      Option
+		typ      string
+		value    interface{}
+		required bool
+	}{
+		{NewOptString("admin", "", true), OptionString, "admin", true},
+		{NewOptInteger(3, "", false), OptionInteger, 3, false},
+		{NewOptBool(true, "", true), OptionBool, true, true},
+		{NewOptEnum("fast", []string{"fast"}, "", false), OptionEnum, "fast", false},
+		{NewOptDict("probe", map[string]string{"probe": "check"}, "", false), OptionDict, "probe", false},
+	}
+
+	for _, tt := range tests {
+		if tt.opt.Type() != tt.typ || tt.opt.Default() != tt.value || tt.opt.IsRequired() != tt.required {
+			t.Errorf("Expected %s option with default %v and required %v, got %s %v %v",
+				tt.typ, tt.value, tt.required, tt.opt.Type(), tt.opt.Default(), tt.opt.IsRequired())
+		}
+	}
+}
+
+func TestResolveOptions(t *testing.T) {
+	declared := map[string]interface{}{
+		"endpoint": NewOptString("admin", "", false),
+		"retries":  NewOptInteger(3, "", false),
+		"verbose":  NewOptBool(false, "", false),
+		"payload":  NewOptDict("probe", map[string]string{"probe": "check", "exploit": "run"}, "", false),
+		"token":    NewOptString("", "", false),
+		"legacy":   "not an api.Option",
+	}
+
+	resolved, err := ResolveOptions(declared, map[string]interface{}{"retries": "5", "extra": "kept"})
+	if err != nil {
+		t.Fatalf("Failed to resolve options: %v", err)
+	}
+	want := map[string]interface{}{
+		"endpoint": "admin",
+		"retries":  5,
+		"verbose":  false,
+		"payload":  "check",
+		"extra":    "kept",
+	}
+	if !reflect.DeepEqual(resolved, want) {
+		t.Errorf("Expected %v, got %v", want, resolved)
+	}
+
+	resolved, err = ResolveOptions(declared, map[string]interface{}{"endpoint": "", "payload": "exploit"})
+	if err != nil {
+		t.Fatalf("Failed to resolve options: %v", err)
+	}
+	if resolved["endpoint"] != "admin" || resolved["payload"] != "run" {
+		t.Errorf("Expected an empty value to fall back to the default, got %v", resolved)
+	}
+
+	_, err = ResolveOptions(declared, map[string]interface{}{"retries": "many"})
+	if err == nil || !strings.Contains(err.Error(), "retries") {
+		t.Errorf("Expected an error naming the invalid option, got %v", err)
+	}
+}
+
+func TestResolveRequiredOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opt     Option
+		values  map[string]interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{"int not supplied", NewOptInteger(0, "", true), nil, nil, true},
+		{"int supplied as zero", NewOptInteger(0, "", true), map[string]interface{}{"opt": "0"}, 0, false},
+		{"int with default not supplied", NewOptInteger(8080, "", true), nil, nil, true},
+		{"bool not supplied", NewOptBool(false, "", true), nil, nil, true},
+		{"bool supplied as false", NewOptBool(false, "", true), map[string]interface{}{"opt": "false"}, false, false},
+		{"string not supplied", NewOptString("", "", true), nil, nil, true},
+		{"string supplied empty", NewOptString("", "", true), map[string]interface{}{"opt": ""}, nil, true},
+		{"string with default not supplied", NewOptString("admin", "", true), nil, nil, true},
+		{"string supplied", NewOptString("", "", true), map[string]interface{}{"opt": "secret"}, "secret", false},
+		{"enum not supplied", NewOptEnum("fast", []string{"fast"}, "", true), nil, nil, true},
+		{"dict supplied", NewOptDict("", map[string]string{"a": "b"}, "", true), map[string]interface{}{"opt": "a"}, "b", false},
+	}
+
+	for _, tt := range tests {
+		resolved, err := ResolveOptions(map[string]interface{}{"opt": tt.opt}, tt.values)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && resolved["opt"] != tt.want {
+			t.Errorf("%s: expected %#v, got %#v", tt.name, tt.want, resolved["opt"])
+		}
+	}
+}
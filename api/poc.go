@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -32,11 +33,23 @@ type POCBase interface {
 	GetOptions() map[string]interface{}
 }
 
+// ContextPOC is implemented by POCs that can be cancelled. The framework
+// runs them through these methods, so that a deadline or an interrupt stops
+// their requests in flight.
+type ContextPOC interface {
+	VerifyContext(ctx context.Context, target string, options map[string]interface{}) (*Output, error)
+	AttackContext(ctx context.Context, target string, options map[string]interface{}) (*Output, error)
+	ShellContext(ctx context.Context, target string, options map[string]interface{}) (*Output, error)
+}
+
 // Output represents the result of POC execution
 type Output struct {
 	Success bool
 	Message string
 	Data    map[string]interface{}
+	// Evidence holds the requests that made the POC succeed, with their
+	// responses.
+	Evidence []Evidence
 }
 
 // NewOutput creates a new Output instance
@@ -61,6 +74,19 @@ func (o *Output) FailOutput(message string) {
 	o.Message = message
 }
 
+// Extracted returns the values the POC extracted, which POCs report under
+// the "Extracted" key of their info, e.g. Data["VerifyInfo"]["Extracted"].
+func (o *Output) Extracted() map[string]interface{} {
+	for _, value := range o.Data {
+		if info, ok := value.(map[string]interface{}); ok {
+			if extracted, ok := info["Extracted"].(map[string]interface{}); ok {
+				return extracted
+			}
+		}
+	}
+	return nil
+}
+
 // String returns string representation of output
 func (o *Output) String() string {
 	if o.Success {
@@ -88,4 +114,23 @@ type POCInfo struct {
 	InstallRequires []string
 	Desc            string
 	PocDesc         string
+	Severity        string
+	Tags            []string
+	Vendor          string
+	Classification  Classification
+}
+
+// Classification identifies a vulnerability in public catalogues
+type Classification struct {
+	CVEID       []string
+	CWEID       []string
+	CVSSScore   float64
+	CVSSMetrics string
+	CPE         string
+}
+
+// InfoProvider is implemented by POCs that expose their full metadata,
+// including the fields POCBase has no getter for.
+type InfoProvider interface {
+	GetInfo() *POCInfo
 }
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// ResultSchemaVersion is the version of the JSON form of Result. It is
+// raised whenever a field is removed, renamed or changes meaning.
+const ResultSchemaVersion = 1
+
+// MaxEvidenceSize caps the request and the response text kept as evidence.
+const MaxEvidenceSize = 4096
+
+// Status is the verdict of running a POC against a target.
+type Status string
+
+const (
+	// StatusVulnerable means the POC matched.
+	StatusVulnerable Status = "vulnerable"
+	// StatusNotVulnerable means the POC ran to the end without matching.
+	StatusNotVulnerable Status = "not_vulnerable"
+	// StatusError means the POC could not run, e.g. the target refused
+	// the connection.
+	StatusError Status = "error"
+	// StatusTimeout means the POC was stopped by a timeout.
+	StatusTimeout Status = "timeout"
+	// StatusSkipped means the POC was stopped before it could finish,
+	// because the scan was cancelled.
+	StatusSkipped Status = "skipped"
+)
+
+// Evidence is a request that made a POC match, along with the response to
+// it. Both are cut to MaxEvidenceSize.
+type Evidence struct {
+	Request   string `json:"request"`
+	Response  string `json:"response"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// NewEvidence returns the evidence of request and response.
+func NewEvidence(request, response string) Evidence {
+	request, requestCut := truncate(request, MaxEvidenceSize)
+	response, responseCut := truncate(response, MaxEvidenceSize)
+
+	return Evidence{
+		Request:   request,
+		Response:  response,
+		Truncated: requestCut || responseCut,
+	}
+}
+
+// truncate cuts s to at most size bytes, without splitting a character.
+func truncate(s string, size int) (string, bool) {
+	if len(s) <= size {
+		return s, false
+	}
+
+	for size > 0 && !utf8.RuneStart(s[size]) {
+		size--
+	}
+	return s[:size], true
+}
+
+// Result is the outcome of running one POC against one target, as recorded
+// by the framework and handed to the result plugins.
+type Result struct {
+	SchemaVersion int    `json:"schema_version"`
+	Target        string `json:"target"`
+	POC           string `json:"poc"`
+	Name          string `json:"name,omitempty"`
+	Mode          string `json:"mode"`
+	Status        Status `json:"status"`
+	Severity      string `json:"severity,omitempty"`
+	// Message says why the POC matched or not, or what went wrong.
+	Message    string    `json:"message,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// DurationMS is how long the POC ran, in milliseconds.
+	DurationMS int64 `json:"duration_ms"`
+	// Extracted holds the values the POC extracted from the target.
+	Extracted map[string]interface{} `json:"extracted,omitempty"`
+	Evidence  []Evidence             `json:"evidence,omitempty"`
+	// Data is the data the POC reported.
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// Duration returns how long the POC ran.
+func (r *Result) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// SetTimes records when the POC started and finished running.
+func (r *Result) SetTimes(started, finished time.Time) {
+	r.StartedAt = started
+	r.FinishedAt = finished
+	r.DurationMS = r.Duration().Milliseconds()
+}
+
+// Vulnerable reports whether the POC matched.
+func (r *Result) Vulnerable() bool {
+	return r.Status == StatusVulnerable
+}
+
+// String returns string representation of result
+func (r *Result) String() string {
+	if r.Vulnerable() {
+		return fmt.Sprintf("[+] %s: %s is vulnerable to %s", r.Status, r.Target, r.POC)
+	}
+	return fmt.Sprintf("[-] %s: %s: %s", r.Status, r.Target, r.Message)
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestResultSetTimes(t *testing.T) {
+	started := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	result := &Result{Target: "http://a", POC: "poc", Status: StatusVulnerable}
+	result.SetTimes(started, started.Add(1500*time.Millisecond))
+
+	if result.Duration() != 1500*time.Millisecond || result.DurationMS != 1500 {
+		t.Errorf("Expected a duration of 1500ms, got %v and %d", result.Duration(), result.DurationMS)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Failed to marshal result: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	if fields["duration_ms"] != 1500.0 || fields["started_at"] != "2024-01-02T03:04:05Z" || fields["finished_at"] != "2024-01-02T03:04:06.5Z" {
+		t.Errorf("Expected the timings in the JSON, got %s", data)
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/seaung/pocsuite-go/yamlpoc"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var convertOutput string
+
+var convertCmd = &cobra.Command{
+	Use:   "convert [file|dir]...",
+	Short: "Convert nuclei templates to pocsuite-go POCs",
+	Long: `Convert nuclei templates to native pocsuite-go YAML. Templates that use constructs
+without a native equivalent are reported and skipped. Converted POCs are written to
+the output directory, named after their file, or printed when no directory is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			fmt.Println("Error: a file or directory to convert is required")
+			cmd.Help()
+			os.Exit(1)
+		}
+
+		files, err := templateFiles(args)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if convertOutput != "" {
+			if err := os.MkdirAll(convertOutput, 0755); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		converted, failed := 0, 0
+		for _, file := range files {
+			if err := convertTemplate(file); err != nil {
+				failed++
+				var convErr *yamlpoc.ConversionError
+				if errors.As(err, &convErr) {
+					fmt.Fprintf(os.Stderr, "[-] %s: cannot be converted:\n", file)
+					for _, problem := range convErr.Problems {
+						fmt.Fprintf(os.Stderr, "    - %s\n", problem)
+					}
+				} else {
+					fmt.Fprintf(os.Stderr, "[-] %s: %v\n", file, err)
+				}
+				continue
+			}
+			converted++
+		}
+
+		fmt.Fprintf(os.Stderr, "[*] %d templates converted, %d failed\n", converted, failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.Flags().StringVarP(&convertOutput, "output", "o", "", "Directory to write the converted POCs to")
+}
+
+func convertTemplate(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	if !yamlpoc.IsNucleiTemplate(data) {
+		return fmt.Errorf("not a nuclei template")
+	}
+
+	poc, err := yamlpoc.ConvertNuclei(data)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(poc); err != nil {
+		return fmt.Errorf("failed to encode POC: %w", err)
+	}
+	encoder.Close()
+
+	if convertOutput == "" {
+		fmt.Printf("# %s\n%s---\n", file, buf.String())
+		return nil
+	}
+
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)) + ".yaml"
+	out := filepath.Join(convertOutput, name)
+	if err := os.WriteFile(out, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "[+] %s -> %s\n", file, out)
+	return nil
+}
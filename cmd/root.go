@@ -1,12 +1,20 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/seaung/pocsuite-go/config"
 	"github.com/seaung/pocsuite-go/lib/core"
+	"github.com/seaung/pocsuite-go/lib/parse"
 	"github.com/seaung/pocsuite-go/modules"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +27,24 @@ var (
 	mode        string
 	optionsFile string
 	consoleMode bool
+	setOptions  []string
+	fingerprint bool
+	urlFile     string
+
+	pocTimeout    time.Duration
+	targetTimeout time.Duration
+
+	outputFile     string
+	checkpointFile string
+	resumeFile     string
+
+	rateLimit       float64
+	hostRateLimit   float64
+	hostConnections int
+
+	// scanConfig holds the settings shared with the pocsuite3 style
+	// command line of lib/parse.
+	scanConfig = &parse.Config{}
 )
 
 var rootCmd = &cobra.Command{
@@ -32,7 +58,12 @@ It supports YAML-based POCs and uses the expr library for expression evaluation.
 			return
 		}
 
-		if target == "" {
+		targets, err := scanTargets()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(targets) == 0 {
 			fmt.Println("Error: target is required")
 			cmd.Help()
 			os.Exit(1)
@@ -45,14 +76,14 @@ It supports YAML-based POCs and uses the expr library for expression evaluation.
 		}
 
 		if pocFile != "" {
-			if err := loadAndExecutePOC(pocFile, target); err != nil {
+			if err := loadAndExecutePOC(pocFile, targets); err != nil {
 				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
 			}
 		}
 
 		if pocDir != "" {
-			if err := loadAndExecutePOCsFromDir(pocDir, target); err != nil {
+			if err := loadAndExecutePOCsFromDir(pocDir, targets); err != nil {
 				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
 			}
@@ -69,38 +100,38 @@ func Execute() {
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&target, "target", "t", "", "Target URL to test")
+	rootCmd.PersistentFlags().StringVarP(&urlFile, "url-file", "f", "", "File of targets to test, one per line")
 	rootCmd.PersistentFlags().StringVarP(&pocFile, "poc", "p", "", "POC file to execute")
 	rootCmd.PersistentFlags().StringVarP(&pocDir, "poc-dir", "d", "", "Directory containing POC files")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
 	rootCmd.PersistentFlags().StringVarP(&mode, "mode", "m", "verify", "Execution mode: verify, attack, shell")
 	rootCmd.PersistentFlags().StringVar(&optionsFile, "options", "", "Options file")
 	rootCmd.PersistentFlags().BoolVar(&consoleMode, "console", false, "Run in interactive console mode")
+	rootCmd.PersistentFlags().StringVar(&scanConfig.ConnectBackHost, "lhost", "", "Connect back host for target PoC in shell mode")
+	rootCmd.PersistentFlags().StringVar(&scanConfig.ConnectBackPort, "lport", "", "Connect back port for target PoC in shell mode")
+	rootCmd.PersistentFlags().StringArrayVar(&setOptions, "set", nil, "Set a POC option as key=value (repeatable)")
+	rootCmd.PersistentFlags().IntVar(&scanConfig.Threads, "threads", 10, "Number of POCs to run concurrently")
+	rootCmd.PersistentFlags().StringVarP(&outputFile, "output", "o", "", "File to record the results to as JSON")
+	rootCmd.PersistentFlags().StringVar(&checkpointFile, "checkpoint", "", "File to record the progress of the scan to, for --resume")
+	rootCmd.PersistentFlags().StringVar(&resumeFile, "resume", "", "Resume the scan recorded in a checkpoint file, skipping the completed tasks")
+	rootCmd.PersistentFlags().DurationVar(&pocTimeout, "poc-timeout", 0, "Maximum run time of one POC, e.g. 30s (0 for no limit)")
+	rootCmd.PersistentFlags().DurationVar(&targetTimeout, "target-timeout", 0, "Maximum run time of all POCs against one target, e.g. 5m (0 for no limit)")
+	rootCmd.PersistentFlags().Float64Var(&rateLimit, "rate-limit", 0, "Maximum requests per second to all hosts (0 for no limit)")
+	rootCmd.PersistentFlags().Float64Var(&hostRateLimit, "host-rate-limit", 0, "Maximum requests per second to one host (0 for no limit)")
+	rootCmd.PersistentFlags().IntVar(&hostConnections, "host-connections", 0, "Maximum concurrent connections to one host (0 for no limit)")
+	rootCmd.PersistentFlags().StringVar(&scanConfig.Delay, "delay", "", "Delay in seconds before each request, fixed or a range (e.g. 1-3)")
+	rootCmd.PersistentFlags().BoolVar(&fingerprint, "fingerprint", false, "Fingerprint the target and only run the POCs for the detected technologies")
 }
 
 func runConsoleMode() {
 	fmt.Println("Starting pocsuite-go in console mode...")
 
-	if err := modules.InitModules(); err != nil {
-		fmt.Printf("Error: Failed to initialize modules: %v\n", err)
-		os.Exit(1)
-	}
-
-	cfg, err := config.NewConfig(config.GetDefaultConfigPath())
+	controller, err := newController()
 	if err != nil {
-		fmt.Printf("Error: Failed to create config: %v\n", err)
-		os.Exit(1)
-	}
-
-	controller, err := core.NewController(cfg)
-	if err != nil {
-		fmt.Printf("Error: Failed to create controller: %v\n", err)
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := controller.Initialize(); err != nil {
-		fmt.Printf("Warning: Failed to initialize controller: %v\n", err)
-	}
-
 	console := core.NewConsole(controller)
 	if err := console.Start(); err != nil {
 		fmt.Printf("Error: %v\n", err)
@@ -112,105 +143,309 @@ func runConsoleMode() {
 	}
 }
 
-func loadAndExecutePOC(pocPath, target string) error {
+func loadAndExecutePOC(pocPath string, targets []string) error {
 	if verbose {
 		fmt.Printf("[*] Loading POC from: %s\n", pocPath)
 	}
 
-	if err := modules.InitModules(); err != nil {
-		return fmt.Errorf("failed to initialize modules: %w", err)
+	controller, err := newController()
+	if err != nil {
+		return err
 	}
 
-	cfg, err := config.NewConfig(config.GetDefaultConfigPath())
+	pocName, err := controller.LoadPOC(pocPath)
 	if err != nil {
-		return fmt.Errorf("failed to create config: %w", err)
+		return fmt.Errorf("failed to load POC: %w", err)
 	}
 
-	controller, err := core.NewController(cfg)
+	if verbose {
+		fmt.Printf("[*] POC loaded: %s\n", pocName)
+	}
+
+	checkpoint, err := openCheckpoint(controller, []string{pocName})
 	if err != nil {
-		return fmt.Errorf("failed to create controller: %w", err)
+		return err
 	}
 
-	if err := controller.Initialize(); err != nil {
-		fmt.Printf("Warning: Failed to initialize controller: %v\n", err)
+	ctx, stop := interruptContext()
+	defer stop()
+
+	summary := runScan(ctx, controller, core.Tasks(targets, []string{pocName}), checkpoint)
+
+	closeCheckpoint(checkpoint, ctx)
+	if err := controller.Shutdown(); err != nil {
+		fmt.Printf("Warning: Failed to shutdown controller: %v\n", err)
 	}
 
-	pocName, err := controller.LoadPOC(pocPath)
-	if err != nil {
-		return fmt.Errorf("failed to load POC: %w", err)
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	if summary.Errors > 0 {
+		return fmt.Errorf("POC execution failed on %d of %d targets", summary.Errors, summary.Tasks)
 	}
 
+	return nil
+}
+
+func loadAndExecutePOCsFromDir(dir string, targets []string) error {
 	if verbose {
-		fmt.Printf("[*] POC loaded: %s\n", pocName)
+		fmt.Printf("[*] Loading POCs from directory: %s\n", dir)
+	}
+
+	controller, err := newController()
+	if err != nil {
+		return err
+	}
+
+	loadedPOCs, err := controller.LoadPOCsFromDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to load POCs from directory: %w", err)
+	}
+
+	if len(loadedPOCs) == 0 {
+		return fmt.Errorf("no POCs loaded from directory")
 	}
 
 	if verbose {
-		fmt.Printf("[*] Executing POC against target: %s\n", target)
-		fmt.Printf("[*] Mode: %s\n", mode)
+		fmt.Printf("[*] Loaded %d POCs\n", len(loadedPOCs))
 	}
 
-	output, err := controller.ExecutePOC(pocName, target, mode)
+	loadedPOCs = controller.StandalonePOCs(loadedPOCs)
+
+	checkpoint, err := openCheckpoint(controller, loadedPOCs)
 	if err != nil {
-		return fmt.Errorf("POC execution failed: %w", err)
+		return err
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	var tasks []core.Task
+	if fingerprint {
+		tasks = fingerprintTasks(ctx, controller, targets, loadedPOCs)
+		if len(tasks) == 0 && ctx.Err() == nil {
+			fmt.Println("[*] No loaded POC applies to the detected technologies")
+			closeCheckpoint(checkpoint, ctx)
+			return controller.Shutdown()
+		}
+	} else {
+		tasks = core.Tasks(targets, loadedPOCs)
 	}
 
-	fmt.Println(output.String())
+	runScan(ctx, controller, tasks, checkpoint)
 
+	closeCheckpoint(checkpoint, ctx)
 	if err := controller.Shutdown(); err != nil {
 		fmt.Printf("Warning: Failed to shutdown controller: %v\n", err)
 	}
 
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
 	return nil
 }
 
-func loadAndExecutePOCsFromDir(dir, target string) error {
-	if verbose {
-		fmt.Printf("[*] Loading POCs from directory: %s\n", dir)
+// openCheckpoint sets up the results file and the checkpoint of a scan
+// running pocs. A resumed scan appends to the results file of the scan it
+// continues, unless told otherwise.
+func openCheckpoint(controller *core.Controller, pocs []string) (*core.Checkpoint, error) {
+	if resumeFile != "" && checkpointFile != "" {
+		return nil, fmt.Errorf("--checkpoint and --resume cannot be used together")
+	}
+
+	if resumeFile != "" {
+		checkpoint, err := controller.ResumeCheckpoint(resumeFile, mode, pocs)
+		if err != nil {
+			return nil, err
+		}
+
+		output := outputFile
+		if output == "" {
+			output = checkpoint.Output()
+		}
+		if output != "" {
+			if err := controller.SetResultFile(output, true); err != nil {
+				checkpoint.Close()
+				return nil, err
+			}
+		}
+
+		fmt.Printf("[*] Resuming %s: %d tasks already complete\n", resumeFile, checkpoint.Completed())
+		return checkpoint, nil
 	}
 
+	output := outputFile
+	if output != "" {
+		if abs, err := filepath.Abs(output); err == nil {
+			output = abs
+		}
+		if err := controller.SetResultFile(output, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if checkpointFile == "" {
+		return nil, nil
+	}
+	return controller.CreateCheckpoint(checkpointFile, mode, pocs, output)
+}
+
+// closeCheckpoint writes out the checkpoint, and tells how to resume a
+// scan that was interrupted.
+func closeCheckpoint(checkpoint *core.Checkpoint, ctx context.Context) {
+	if checkpoint == nil {
+		return
+	}
+
+	if err := checkpoint.Close(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return
+	}
+
+	if ctx.Err() != nil {
+		fmt.Printf("[*] Continue the scan with --resume %s\n", checkpoint.Path())
+	}
+}
+
+var errInterrupted = errors.New("scan interrupted")
+
+// interruptContext returns a context that is cancelled on the first SIGINT
+// or SIGTERM, so that the scan stops and the results so far still reach the
+// report plugins. A second signal kills the process.
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			fmt.Println("\n[!] Interrupted, stopping the scan (interrupt again to quit at once)")
+			signal.Stop(signals)
+			cancel(errInterrupted)
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel(nil)
+	}
+}
+
+func newController() (*core.Controller, error) {
 	if err := modules.InitModules(); err != nil {
-		return fmt.Errorf("failed to initialize modules: %w", err)
+		return nil, fmt.Errorf("failed to initialize modules: %w", err)
 	}
 
 	cfg, err := config.NewConfig(config.GetDefaultConfigPath())
 	if err != nil {
-		return fmt.Errorf("failed to create config: %w", err)
+		return nil, fmt.Errorf("failed to create config: %w", err)
 	}
 
 	controller, err := core.NewController(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create controller: %w", err)
+		return nil, fmt.Errorf("failed to create controller: %w", err)
 	}
 
 	if err := controller.Initialize(); err != nil {
 		fmt.Printf("Warning: Failed to initialize controller: %v\n", err)
 	}
+	controller.SetConnectBack(scanConfig.ConnectBackHost, scanConfig.ConnectBackPort)
+	if err := applyRateLimit(controller); err != nil {
+		return nil, err
+	}
+	if err := applyOptions(controller); err != nil {
+		return nil, err
+	}
 
-	loadedPOCs, err := controller.LoadPOCsFromDir(dir)
+	return controller, nil
+}
+
+// applyRateLimit sets the request limits of the RateLimit config section,
+// overridden by the ones set on the command line.
+func applyRateLimit(controller *core.Controller) error {
+	limits, err := controller.RateLimit()
 	if err != nil {
-		return fmt.Errorf("failed to load POCs from directory: %w", err)
+		return err
 	}
 
-	if len(loadedPOCs) == 0 {
-		return fmt.Errorf("no POCs loaded from directory")
+	if rateLimit > 0 {
+		limits.RequestsPerSecond = rateLimit
+	}
+	if hostRateLimit > 0 {
+		limits.HostRequestsPerSecond = hostRateLimit
+	}
+	if hostConnections > 0 {
+		limits.MaxHostConnections = hostConnections
+	}
+	if scanConfig.Delay != "" {
+		limits.Delay = scanConfig.Delay
 	}
 
-	if verbose {
-		fmt.Printf("[*] Loaded %d POCs\n", len(loadedPOCs))
+	return controller.SetRateLimit(limits)
+}
+
+// fingerprintTasks pairs each target with the POCs for the technologies
+// detected on it. Targets are fingerprinted on the scan workers.
+func fingerprintTasks(ctx context.Context, controller *core.Controller, targets, pocs []string) []core.Task {
+	var tasks []core.Task
+
+	scanner := newScanner(controller)
+	scanner.Fingerprint(ctx, targets, func(result *core.FingerprintResult) {
+		if result.Err != nil {
+			fmt.Printf("[-] Failed to fingerprint %s: %v\n", result.Target, result.Err)
+			return
+		}
+
+		for _, tech := range result.Technologies {
+			detected := strings.TrimSpace(tech.Name + " " + tech.Version)
+			if tech.CPE != "" {
+				detected += " (" + tech.CPE + ")"
+			}
+			fmt.Printf("[*] Detected on %s: %s\n", result.Target, detected)
+		}
+
+		tasks = append(tasks, core.Tasks([]string{result.Target}, controller.SelectPOCs(pocs, result.Technologies))...)
+	})
+
+	return tasks
+}
+
+// newScanner returns a scanner set up from the command line.
+func newScanner(controller *core.Controller) *core.Scanner {
+	scanner := core.NewScanner(controller, scanConfig.Threads, mode)
+	scanner.SetTimeouts(pocTimeout, targetTimeout)
+	return scanner
+}
+
+// runScan runs tasks on the scan engine, printing the results in task
+// order, and a summary when there was more than one task.
+func runScan(ctx context.Context, controller *core.Controller, tasks []core.Task, checkpoint *core.Checkpoint) *core.ScanSummary {
+	if checkpoint != nil {
+		tasks = checkpoint.Remaining(tasks)
 	}
 
-	successCount := 0
-	for _, pocName := range loadedPOCs {
-		fmt.Printf("\n[*] Processing: %s\n", pocName)
+	if verbose {
+		fmt.Printf("[*] Running %d tasks on %d threads\n", len(tasks), scanConfig.Threads)
+		fmt.Printf("[*] Mode: %s\n", mode)
+	}
 
-		output, err := controller.ExecutePOC(pocName, target, mode)
-		if err != nil {
-			fmt.Printf("[-] Error: %v\n", err)
-			continue
+	scanner := newScanner(controller)
+	scanner.SetCheckpoint(checkpoint)
+	summary := scanner.Run(ctx, tasks, func(result *core.ScanResult) {
+		fmt.Printf("\n[*] Processing: %s against %s\n", result.POC, result.Target)
+		if result.Err != nil {
+			fmt.Printf("[-] Error: %v\n", result.Err)
+			return
 		}
+		fmt.Println(result.Output.String())
+	})
 
-		fmt.Println(output.String())
-		successCount++
+	if summary.Tasks < 2 {
+		return summary
 	}
 
 	table := tablewriter.NewTable(os.Stdout,
@@ -220,18 +455,55 @@ func loadAndExecutePOCsFromDir(dir, target string) error {
 	table.Header("Metric", "Value")
 
 	var rows [][]any
-	rows = append(rows, []any{"Total POCs", fmt.Sprintf("%d", len(loadedPOCs))})
-	rows = append(rows, []any{"Successful", fmt.Sprintf("%d", successCount)})
-	rows = append(rows, []any{"Failed", fmt.Sprintf("%d", len(loadedPOCs)-successCount)})
-	rows = append(rows, []any{"Success Rate", fmt.Sprintf("%.1f%%", float64(successCount)/float64(len(loadedPOCs))*100)})
+	rows = append(rows, []any{"Targets", fmt.Sprintf("%d", summary.Targets)})
+	rows = append(rows, []any{"Total POCs", fmt.Sprintf("%d", summary.POCs)})
+	rows = append(rows, []any{"Tasks", fmt.Sprintf("%d", summary.Tasks)})
+	rows = append(rows, []any{"Successful", fmt.Sprintf("%d", summary.Succeeded)})
+	rows = append(rows, []any{"Failed", fmt.Sprintf("%d", summary.Failed)})
+	rows = append(rows, []any{"Errors", fmt.Sprintf("%d", summary.Errors)})
+	if summary.Skipped > 0 {
+		rows = append(rows, []any{"Skipped", fmt.Sprintf("%d", summary.Skipped)})
+	}
+	rows = append(rows, []any{"Success Rate", fmt.Sprintf("%.1f%%", float64(summary.Succeeded)/float64(summary.Tasks)*100)})
+	rows = append(rows, []any{"Duration", summary.Duration.Round(time.Millisecond).String()})
 	table.Bulk(rows)
 
 	fmt.Printf("\n[*] Execution Summary:\n")
 	table.Render()
 
-	if err := controller.Shutdown(); err != nil {
-		fmt.Printf("Warning: Failed to shutdown controller: %v\n", err)
+	return summary
+}
+
+// scanTargets returns the target given with --target followed by the ones
+// listed in --url-file, one per line.
+func scanTargets() ([]string, error) {
+	var targets []string
+	if target != "" {
+		targets = append(targets, target)
+	}
+
+	if urlFile != "" {
+		data, err := os.ReadFile(urlFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read targets: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				targets = append(targets, line)
+			}
+		}
 	}
 
+	return targets, nil
+}
+
+func applyOptions(controller *core.Controller) error {
+	for _, option := range setOptions {
+		key, value, ok := strings.Cut(option, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid option %q, expected key=value", option)
+		}
+		controller.SetOption(key, value)
+	}
 	return nil
 }
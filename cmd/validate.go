@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/seaung/pocsuite-go/yamlpoc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validateJSON   bool
+	validateStrict bool
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [file|dir]...",
+	Short: "Validate POC templates without running them",
+	Long: `Strictly decode POC templates and check matcher and extractor types, part names,
+expression syntax, undefined variables and required info fields. Issues are reported
+as file:line:column, and the command exits non-zero when any error is found.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		paths := args
+		if pocFile != "" {
+			paths = append(paths, pocFile)
+		}
+		if pocDir != "" {
+			paths = append(paths, pocDir)
+		}
+		if len(paths) == 0 {
+			fmt.Println("Error: a file or directory to validate is required")
+			cmd.Help()
+			os.Exit(1)
+		}
+
+		report, err := validateTemplates(paths)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if validateJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			for _, issue := range report.Issues {
+				fmt.Printf("%s:%s\n", issue.File, issue.Issue)
+			}
+			fmt.Printf("[*] %d templates checked: %d errors, %d warnings\n", report.Files, report.Errors, report.Warnings)
+		}
+
+		if report.Errors > 0 || (validateStrict && report.Warnings > 0) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().BoolVar(&validateJSON, "json", false, "Print the report as JSON")
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "Treat warnings as errors")
+}
+
+type validateIssue struct {
+	File string `json:"file"`
+	yamlpoc.Issue
+}
+
+type validateReport struct {
+	Files    int             `json:"files"`
+	Errors   int             `json:"errors"`
+	Warnings int             `json:"warnings"`
+	Issues   []validateIssue `json:"issues"`
+}
+
+func validateTemplates(paths []string) (*validateReport, error) {
+	report := &validateReport{Issues: []validateIssue{}}
+
+	files, err := templateFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		issues, err := yamlpoc.LintFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Files++
+		for _, issue := range issues {
+			if issue.Severity == yamlpoc.SeverityError {
+				report.Errors++
+			} else {
+				report.Warnings++
+			}
+			report.Issues = append(report.Issues, validateIssue{File: file, Issue: issue})
+		}
+	}
+
+	return report, nil
+}
+
+// templateFiles expands paths into the YAML files they name, walking
+// directories recursively.
+func templateFiles(paths []string) ([]string, error) {
+	var files []string
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			ext := strings.ToLower(filepath.Ext(file))
+			if !info.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk directory: %w", err)
+		}
+	}
+
+	return files, nil
+}
@@ -3,7 +3,10 @@ module github.com/seaung/pocsuite-go
 go 1.24.0
 
 require (
+	github.com/antchfx/htmlquery v1.3.6
+	github.com/antchfx/xmlquery v1.5.1
 	github.com/expr-lang/expr v1.16.9
+	github.com/miekg/dns v1.1.72
 	github.com/olekukonko/tablewriter v1.1.2
 	github.com/spf13/cobra v1.8.0
 	golang.org/x/net v0.48.0
@@ -11,10 +14,12 @@ require (
 )
 
 require (
+	github.com/antchfx/xpath v1.3.6 // indirect
 	github.com/clipperhouse/displaywidth v0.6.0 // indirect
 	github.com/clipperhouse/stringish v0.1.1 // indirect
 	github.com/clipperhouse/uax29/v2 v2.3.0 // indirect
 	github.com/fatih/color v1.15.0 // indirect
+	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
@@ -23,5 +28,9 @@ require (
 	github.com/olekukonko/errors v1.1.0 // indirect
 	github.com/olekukonko/ll v0.1.3 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/mod v0.31.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
 	golang.org/x/sys v0.39.0 // indirect
+	golang.org/x/text v0.32.0 // indirect
+	golang.org/x/tools v0.40.0 // indirect
 )
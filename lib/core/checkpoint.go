@@ -0,0 +1,321 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seaung/pocsuite-go/registry"
+)
+
+const (
+	checkpointVersion = 1
+
+	// checkpointFlushInterval is how often completed tasks are written
+	// through to disk.
+	checkpointFlushInterval = 5 * time.Second
+)
+
+// checkpointHeader is the first line of a checkpoint file.
+type checkpointHeader struct {
+	Version    int               `json:"version"`
+	ConfigHash string            `json:"config_hash"`
+	Mode       string            `json:"mode"`
+	POCs       []string          `json:"pocs"`
+	Digests    map[string]string `json:"digests,omitempty"`
+	Output     string            `json:"output,omitempty"`
+	Created    time.Time         `json:"created"`
+}
+
+// Checkpoint records the tasks of a scan that are complete, so that an
+// interrupted scan can be resumed without running them again. The file
+// holds a JSON header line with the hash of the scan configuration, then
+// one JSON line per completed task; a line cut short by a crash is
+// ignored.
+type Checkpoint struct {
+	path   string
+	header checkpointHeader
+
+	mu        sync.Mutex
+	done      map[Task]bool
+	file      *os.File
+	writer    *bufio.Writer
+	lastFlush time.Time
+	err       error
+}
+
+// CreateCheckpoint starts a new checkpoint at path for a scan running pocs
+// in mode with the controller's options. output is the results file of the
+// scan, which a resumed scan appends to.
+func (c *Controller) CreateCheckpoint(path, mode string, pocs []string, output string) (*Checkpoint, error) {
+	header := checkpointHeader{
+		Version:    checkpointVersion,
+		ConfigHash: c.ConfigHash(mode, pocs),
+		Mode:       mode,
+		POCs:       sortedCopy(pocs),
+		Digests:    c.pocDigests(pocs),
+		Output:     output,
+		Created:    time.Now(),
+	}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+
+	cp := newCheckpoint(path, header, file)
+	cp.writer.Write(append(data, '\n'))
+	if err := cp.flush(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return cp, nil
+}
+
+// ResumeCheckpoint opens the checkpoint at path to continue its scan. It
+// refuses to when the POC set, the content of a template, the mode or the
+// options differ from the ones the checkpoint was created with.
+func (c *Controller) ResumeCheckpoint(path, mode string, pocs []string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+
+	var header checkpointHeader
+	if err := json.Unmarshal(lines[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	if header.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version %d", header.Version)
+	}
+
+	if hash := c.ConfigHash(mode, pocs); hash != header.ConfigHash {
+		added, removed := diffPOCs(header.POCs, pocs)
+		changed := changedPOCs(header.Digests, c.pocDigests(pocs))
+		switch {
+		case len(added) > 0 || len(removed) > 0:
+			return nil, fmt.Errorf("cannot resume %s: the POC set changed (%d added, %d removed)", path, len(added), len(removed))
+		case mode != header.Mode:
+			return nil, fmt.Errorf("cannot resume %s: it was made in %s mode", path, header.Mode)
+		case len(changed) > 0:
+			return nil, fmt.Errorf("cannot resume %s: the templates of %s changed", path, strings.Join(changed, ", "))
+		default:
+			return nil, fmt.Errorf("cannot resume %s: the POC options changed", path)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+
+	cp := newCheckpoint(path, header, file)
+	for _, line := range lines[1:] {
+		var task Task
+		if json.Unmarshal(line, &task) == nil && task.Target != "" && task.POC != "" {
+			cp.done[task] = true
+		}
+	}
+
+	// Start on a line of our own after a partly written one.
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		cp.writer.WriteByte('\n')
+	}
+
+	return cp, nil
+}
+
+func newCheckpoint(path string, header checkpointHeader, file *os.File) *Checkpoint {
+	return &Checkpoint{
+		path:      path,
+		header:    header,
+		done:      make(map[Task]bool),
+		file:      file,
+		writer:    bufio.NewWriter(file),
+		lastFlush: time.Now(),
+	}
+}
+
+// Path returns the checkpoint file.
+func (cp *Checkpoint) Path() string {
+	return cp.path
+}
+
+// Output returns the results file of the checkpointed scan.
+func (cp *Checkpoint) Output() string {
+	return cp.header.Output
+}
+
+// Completed returns the number of tasks recorded as complete.
+func (cp *Checkpoint) Completed() int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return len(cp.done)
+}
+
+// Remaining returns the tasks that are not complete yet, in order.
+func (cp *Checkpoint) Remaining(tasks []Task) []Task {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	remaining := make([]Task, 0, len(tasks))
+	for _, task := range tasks {
+		if !cp.done[task] {
+			remaining = append(remaining, task)
+		}
+	}
+	return remaining
+}
+
+// Complete records task as complete. Records reach the disk at least every
+// checkpointFlushInterval, and on Close. A write error stops the recording
+// and is returned by Close.
+func (cp *Checkpoint) Complete(task Task) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if cp.err != nil || cp.done[task] {
+		return
+	}
+	cp.done[task] = true
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		cp.err = fmt.Errorf("failed to encode checkpoint: %w", err)
+		return
+	}
+	cp.writer.Write(append(data, '\n'))
+
+	if time.Since(cp.lastFlush) >= checkpointFlushInterval {
+		cp.err = cp.flush()
+	}
+}
+
+func (cp *Checkpoint) flush() error {
+	cp.lastFlush = time.Now()
+
+	if err := cp.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := cp.file.Sync(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Close writes out the pending records and closes the file.
+func (cp *Checkpoint) Close() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if cp.file == nil {
+		return cp.err
+	}
+
+	if cp.err == nil {
+		cp.err = cp.flush()
+	}
+	if err := cp.file.Close(); err != nil && cp.err == nil {
+		cp.err = fmt.Errorf("failed to close checkpoint: %w", err)
+	}
+	cp.file = nil
+
+	return cp.err
+}
+
+// ConfigHash identifies what a scan runs: the mode, the POC set along with
+// the content of their templates, and the options the POCs get. The targets
+// are left out, so that a resumed scan may be given more of them.
+func (c *Controller) ConfigHash(mode string, pocs []string) string {
+	digests := c.pocDigests(pocs)
+
+	options := c.optionsSnapshot()
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "mode=%s\n", mode)
+	for _, poc := range sortedCopy(pocs) {
+		fmt.Fprintf(h, "poc=%s %s\n", poc, digests[poc])
+	}
+	for _, k := range keys {
+		fmt.Fprintf(h, "option=%s=%v\n", k, options[k])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// pocDigests returns the digest of the template of each of pocs. The digest
+// of a workflow covers the templates it runs as well.
+func (c *Controller) pocDigests(pocs []string) map[string]string {
+	digests := make(map[string]string, len(pocs))
+	for _, name := range pocs {
+		digest := c.pocLoader.Digest(name)
+		if poc, exists := registry.Get(name); exists {
+			if workflow, ok := poc.(*registry.WorkflowWrapper); ok {
+				for _, member := range sortedCopy(workflow.Templates()) {
+					digest += "," + member + ":" + c.pocLoader.Digest(member)
+				}
+			}
+		}
+		digests[name] = digest
+	}
+	return digests
+}
+
+// changedPOCs returns the POCs whose digest differs between previous and
+// current, sorted.
+func changedPOCs(previous, current map[string]string) []string {
+	var changed []string
+	for poc, digest := range current {
+		if previous[poc] != digest {
+			changed = append(changed, poc)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// diffPOCs returns the POCs of current missing from previous, and the ones
+// of previous missing from current.
+func diffPOCs(previous, current []string) (added, removed []string) {
+	seen := make(map[string]bool, len(previous))
+	for _, poc := range previous {
+		seen[poc] = true
+	}
+	for _, poc := range current {
+		if !seen[poc] {
+			added = append(added, poc)
+		}
+		delete(seen, poc)
+	}
+	for poc := range seen {
+		removed = append(removed, poc)
+	}
+	return added, removed
+}
@@ -0,0 +1,225 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const checkpointPOC = `id: %s
+info:
+  name: %s
+requests:
+  - method: GET
+    path: /
+    matchers:
+      - type: word
+        words:
+          - "%s"
+`
+
+// loadCheckpointPOCs writes a template per name to a directory, matching
+// the word given for the name, and loads them into controller. The templates are unloaded at the end of the test.
+func loadCheckpointPOCs(t *testing.T, controller *Controller, dir string, words map[string]string) []string {
+	t.Helper()
+
+	for name, word := range words {
+		content := fmt.Sprintf(checkpointPOC, name, name, word)
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write template: %v", err)
+		}
+	}
+
+	pocs, err := controller.LoadPOCsFromDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+	t.Cleanup(controller.ClearPOCs)
+	return pocs
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+func TestCheckpointResume(t *testing.T) {
+	controller, _ := newTestController(t)
+	pocs := loadCheckpointPOCs(t, controller, t.TempDir(), map[string]string{"cp-a": "a", "cp-b": "b"})
+	path := filepath.Join(t.TempDir(), "scan.checkpoint")
+
+	cp, err := controller.CreateCheckpoint(path, "verify", pocs, "/tmp/results.json")
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint: %v", err)
+	}
+
+	tasks := Tasks([]string{"http://1", "http://2"}, []string{"cp-a", "cp-b"})
+	cp.Complete(tasks[0])
+	cp.Complete(tasks[2])
+	cp.Complete(tasks[0])
+	if err := cp.Close(); err != nil {
+		t.Fatalf("Failed to close checkpoint: %v", err)
+	}
+
+	lines := readLines(t, path)
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and two tasks, got %q", lines)
+	}
+	if want := `{"target":"http://1","poc":"cp-a"}`; lines[1] != want {
+		t.Errorf("Expected task line %s, got %s", want, lines[1])
+	}
+
+	resumed, err := controller.ResumeCheckpoint(path, "verify", pocs)
+	if err != nil {
+		t.Fatalf("Failed to resume checkpoint: %v", err)
+	}
+	defer resumed.Close()
+
+	if resumed.Completed() != 2 || resumed.Output() != "/tmp/results.json" {
+		t.Errorf("Expected 2 tasks complete and the results file, got %d and %q", resumed.Completed(), resumed.Output())
+	}
+	if got, want := resumed.Remaining(tasks), []Task{tasks[1], tasks[3]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the remaining tasks %v, got %v", want, got)
+	}
+}
+
+func TestCheckpointTruncatedLine(t *testing.T) {
+	controller, _ := newTestController(t)
+	pocs := loadCheckpointPOCs(t, controller, t.TempDir(), map[string]string{"cp-a": "a"})
+	path := filepath.Join(t.TempDir(), "scan.checkpoint")
+
+	cp, err := controller.CreateCheckpoint(path, "verify", pocs, "")
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint: %v", err)
+	}
+	cp.Complete(Task{Target: "http://1", POC: "cp-a"})
+	if err := cp.Close(); err != nil {
+		t.Fatalf("Failed to close checkpoint: %v", err)
+	}
+
+	// A crash in the middle of writing a record.
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open checkpoint: %v", err)
+	}
+	file.WriteString(`{"target":"http://2","po`)
+	file.Close()
+
+	resumed, err := controller.ResumeCheckpoint(path, "verify", pocs)
+	if err != nil {
+		t.Fatalf("Failed to resume checkpoint: %v", err)
+	}
+	if resumed.Completed() != 1 {
+		t.Errorf("Expected the cut line to be ignored, got %d tasks complete", resumed.Completed())
+	}
+	resumed.Complete(Task{Target: "http://3", POC: "cp-a"})
+	if err := resumed.Close(); err != nil {
+		t.Fatalf("Failed to close checkpoint: %v", err)
+	}
+
+	resumed, err = controller.ResumeCheckpoint(path, "verify", pocs)
+	if err != nil {
+		t.Fatalf("Failed to resume checkpoint again: %v", err)
+	}
+	defer resumed.Close()
+
+	remaining := resumed.Remaining(Tasks([]string{"http://1", "http://2", "http://3"}, pocs))
+	if want := []Task{{Target: "http://2", POC: "cp-a"}}; !reflect.DeepEqual(remaining, want) {
+		t.Errorf("Expected the record after the cut line to be read, remaining %v", remaining)
+	}
+}
+
+func TestCheckpointResumeRejectsChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, controller *Controller, dir string) (mode string, pocs []string)
+		want   string
+	}{
+		{
+			name: "mode",
+			change: func(t *testing.T, controller *Controller, dir string) (string, []string) {
+				return "attack", []string{"cp-a", "cp-b"}
+			},
+			want: "made in verify mode",
+		},
+		{
+			name: "POC set",
+			change: func(t *testing.T, controller *Controller, dir string) (string, []string) {
+				return "verify", []string{"cp-a"}
+			},
+			want: "the POC set changed (0 added, 1 removed)",
+		},
+		{
+			name: "options",
+			change: func(t *testing.T, controller *Controller, dir string) (string, []string) {
+				controller.SetOption("username", "admin")
+				return "verify", []string{"cp-a", "cp-b"}
+			},
+			want: "the POC options changed",
+		},
+		{
+			name: "template content",
+			change: func(t *testing.T, controller *Controller, dir string) (string, []string) {
+				controller.ClearPOCs()
+				return "verify", loadCheckpointPOCs(t, controller, dir, map[string]string{"cp-a": "a", "cp-b": "changed"})
+			},
+			want: "the templates of cp-b changed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller, _ := newTestController(t)
+			dir := t.TempDir()
+			pocs := loadCheckpointPOCs(t, controller, dir, map[string]string{"cp-a": "a", "cp-b": "b"})
+			path := filepath.Join(t.TempDir(), "scan.checkpoint")
+
+			cp, err := controller.CreateCheckpoint(path, "verify", pocs, "")
+			if err != nil {
+				t.Fatalf("Failed to create checkpoint: %v", err)
+			}
+			cp.Close()
+
+			if resumed, err := controller.ResumeCheckpoint(path, "verify", pocs); err != nil {
+				t.Fatalf("Expected an unchanged scan to resume, got %v", err)
+			} else {
+				resumed.Close()
+			}
+
+			mode, changed := tt.change(t, controller, dir)
+			_, err = controller.ResumeCheckpoint(path, mode, changed)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestConfigHash(t *testing.T) {
+	controller, _ := newTestController(t)
+	pocs := loadCheckpointPOCs(t, controller, t.TempDir(), map[string]string{"cp-a": "a", "cp-b": "b"})
+
+	hash := controller.ConfigHash("verify", pocs)
+	if got := controller.ConfigHash("verify", []string{pocs[1], pocs[0]}); got != hash {
+		t.Error("Expected the hash not to depend on the POC order")
+	}
+	if controller.pocLoader.Digest("cp-a") == "" || controller.pocLoader.Digest("cp-a") == controller.pocLoader.Digest("cp-b") {
+		t.Error("Expected a distinct digest per template")
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/olekukonko/tablewriter"
+	"github.com/seaung/pocsuite-go/api"
 	"github.com/seaung/pocsuite-go/registry"
 )
 
@@ -86,8 +88,13 @@ func (c *Console) executeCommand(line string) error {
 		c.cmdExit()
 	case "search":
 		return c.cmdSearch(args)
-	case "list", "show":
+	case "list":
 		return c.cmdList(args)
+	case "show":
+		if len(args) == 0 || strings.ToLower(args[0]) == "all" {
+			return c.cmdList(args)
+		}
+		return c.cmdShow(args)
 	case "use":
 		return c.cmdUse(args)
 	case "set":
@@ -98,6 +105,8 @@ func (c *Console) executeCommand(line string) error {
 		return c.cmdCheck(args)
 	case "attack":
 		return c.cmdAttack(args)
+	case "shell":
+		return c.cmdShell(args)
 	case "results":
 		c.cmdResults()
 	case "clear":
@@ -134,6 +143,7 @@ Global commands:
   run <target>            Run selected POC against target
   check <target>          Check if target is vulnerable
   attack <target>         Attack target
+  shell <target>          Get a reverse shell (set lhost/lport first)
   results                 Show all results
   clear                   Clear the screen
 
@@ -243,6 +253,17 @@ func (c *Console) cmdSet(args []string) error {
 
 	key := args[0]
 	value := strings.Join(args[1:], " ")
+
+	if pocName, ok := c.controller.GetOption("current_poc"); ok {
+		if poc, exists := registry.Get(pocName.(string)); exists {
+			if opt, ok := poc.GetOptions()[key].(api.Option); ok {
+				if _, err := opt.Convert(value); err != nil {
+					return fmt.Errorf("invalid value for option '%s': %w", key, err)
+				}
+			}
+		}
+	}
+
 	c.controller.SetOption(key, value)
 	fmt.Printf("Set %s = %s\n", key, value)
 
@@ -314,6 +335,26 @@ func (c *Console) cmdAttack(args []string) error {
 	return nil
 }
 
+func (c *Console) cmdShell(args []string) error {
+	pocName, ok := c.controller.GetOption("current_poc")
+	if !ok {
+		return fmt.Errorf("no POC selected. Use 'use <poc>' first")
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("target is required")
+	}
+
+	target := args[0]
+	output, err := c.controller.ExecutePOC(pocName.(string), target, "shell")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(output.String())
+	return nil
+}
+
 func (c *Console) cmdResults() {
 	results := c.controller.GetResults()
 
@@ -326,25 +367,22 @@ func (c *Console) cmdResults() {
 		tablewriter.WithMaxWidth(120),
 		tablewriter.WithColumnMax(50),
 	)
-	table.Header("#", "Status", "Message", "Data")
+	table.Header("#", "Target", "POC", "Status", "Duration", "Message")
 
 	var rows [][]any
 	for i, result := range results {
-		status := "✗"
-		if result.Success {
-			status = "✓"
-		}
-
-		dataStr := fmt.Sprintf("%v", result.Data)
-		if len(dataStr) > 40 {
-			dataStr = dataStr[:40] + "..."
+		status := "✗ " + string(result.Status)
+		if result.Vulnerable() {
+			status = "✓ " + string(result.Status)
 		}
 
 		rows = append(rows, []any{
 			fmt.Sprintf("%d", i+1),
+			result.Target,
+			result.POC,
 			status,
+			fmt.Sprintf("%d ms", result.DurationMS),
 			result.Message,
-			dataStr,
 		})
 	}
 	table.Bulk(rows)
@@ -431,11 +469,7 @@ func (c *Console) cmdShow(args []string) error {
 		table.Render()
 
 	case "options", "option":
-		if pocName, ok := c.controller.GetOption("current_poc"); ok {
-			fmt.Printf("Current POC: %s\n", pocName)
-		} else {
-			fmt.Println("No POC selected")
-		}
+		return c.showOptions()
 
 	case "results", "result":
 		c.cmdResults()
@@ -447,6 +481,64 @@ func (c *Console) cmdShow(args []string) error {
 	return nil
 }
 
+func (c *Console) showOptions() error {
+	pocName, ok := c.controller.GetOption("current_poc")
+	if !ok {
+		fmt.Println("No POC selected")
+		return nil
+	}
+
+	poc, exists := registry.Get(pocName.(string))
+	if !exists {
+		return fmt.Errorf("POC '%s' not found", pocName)
+	}
+
+	fmt.Printf("Current POC: %s\n", pocName)
+
+	declared := poc.GetOptions()
+	if len(declared) == 0 {
+		fmt.Println("This POC has no options")
+		return nil
+	}
+
+	names := make([]string, 0, len(declared))
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	table := tablewriter.NewTable(os.Stdout,
+		tablewriter.WithMaxWidth(120),
+		tablewriter.WithColumnMax(40),
+	)
+	table.Header("Name", "Current Setting", "Type", "Required", "Description")
+
+	var rows [][]any
+	for _, name := range names {
+		opt, ok := declared[name].(api.Option)
+		if !ok {
+			rows = append(rows, []any{name, fmt.Sprintf("%v", declared[name]), "", "", ""})
+			continue
+		}
+
+		current := opt.Default()
+		if value, ok := c.controller.GetOption(name); ok {
+			current = value
+		}
+
+		required := "no"
+		if opt.IsRequired() {
+			required = "yes"
+		}
+
+		rows = append(rows, []any{name, fmt.Sprintf("%v", current), opt.Type(), required, opt.Description()})
+	}
+	table.Bulk(rows)
+	table.Render()
+
+	return nil
+}
+
 func (c *Console) cmdListener(args []string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("usage: listener <start|stop|list|clients|send|read> [args...]")
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -17,6 +19,13 @@ import (
 	"github.com/seaung/pocsuite-go/modules/plugins"
 	"github.com/seaung/pocsuite-go/modules/spider"
 	"github.com/seaung/pocsuite-go/registry"
+	"github.com/seaung/pocsuite-go/request"
+	"github.com/seaung/pocsuite-go/yamlpoc"
+)
+
+const (
+	defaultConnectBackPort = "4444"
+	defaultShellTimeout    = 10 * time.Second
 )
 
 type Controller struct {
@@ -27,9 +36,19 @@ type Controller struct {
 	listenerMgr   *listener.ListenerManager
 	spiderMgr     *spider.Spider
 	httpServerMgr *httpserver.HTTPServer
-	results       []*api.Output
+	results       []*api.Result
 	mu            sync.RWMutex
 	options       map[string]interface{}
+	// pluginMu serializes result notifications, so that concurrent POCs do
+	// not interleave their output in the result plugins.
+	pluginMu sync.Mutex
+	// shellMu guards starting the connect-back listener and claiming the
+	// sessions that reach it.
+	shellMu       sync.Mutex
+	shellSessions map[interface{}]bool
+	// fingerprintMu guards initializing the fingerprint module, which
+	// targets may be fingerprinted concurrently with.
+	fingerprintMu sync.Mutex
 }
 
 func NewController(cfg *config.Config) (*Controller, error) {
@@ -48,8 +67,9 @@ func NewController(cfg *config.Config) (*Controller, error) {
 		listenerMgr:   listenerMgr,
 		spiderMgr:     spiderMgr,
 		httpServerMgr: httpServerMgr,
-		results:       make([]*api.Output, 0),
+		results:       make([]*api.Result, 0),
 		options:       make(map[string]interface{}),
+		shellSessions: make(map[interface{}]bool),
 	}, nil
 }
 
@@ -85,6 +105,19 @@ func (c *Controller) GetOption(key string) (interface{}, bool) {
 	return val, ok
 }
 
+// optionsSnapshot returns a copy of the options, which each POC run owns
+// and may change freely.
+func (c *Controller) optionsSnapshot() map[string]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	options := make(map[string]interface{}, len(c.options))
+	for k, v := range c.options {
+		options[k] = v
+	}
+	return options
+}
+
 func (c *Controller) LoadPOC(pocPath string) (string, error) {
 	return c.pocLoader.LoadFromFile(pocPath)
 }
@@ -109,22 +142,47 @@ func (c *Controller) ClearPOCs() {
 	c.pocLoader.Clear()
 }
 
+// StandalonePOCs filters names down to the POCs to run on their own,
+// leaving the templates of the loaded workflows to their workflow.
+func (c *Controller) StandalonePOCs(names []string) []string {
+	members := make(map[string]bool)
+	for _, name := range names {
+		if poc, exists := registry.Get(name); exists {
+			if workflow, ok := poc.(*registry.WorkflowWrapper); ok {
+				for _, member := range workflow.Templates() {
+					members[member] = true
+				}
+			}
+		}
+	}
+
+	standalone := make([]string, 0, len(names))
+	for _, name := range names {
+		if !members[name] {
+			standalone = append(standalone, name)
+		}
+	}
+	return standalone
+}
+
 func (c *Controller) GetPOCCount() int {
 	return c.pocLoader.Count()
 }
 
 func (c *Controller) ExecutePOC(pocName, target string, mode string) (*api.Output, error) {
+	return c.ExecutePOCContext(context.Background(), pocName, target, mode)
+}
+
+// ExecutePOCContext is ExecutePOC, giving up as soon as ctx is done. Every
+// run of the POC is recorded as a result and handed to the result plugins,
+// including the ones that failed or were stopped.
+func (c *Controller) ExecutePOCContext(ctx context.Context, pocName, target string, mode string) (*api.Output, error) {
 	poc, exists := registry.Get(pocName)
 	if !exists {
 		return nil, fmt.Errorf("POC '%s' not found", pocName)
 	}
 
-	options := make(map[string]interface{})
-	c.mu.RLock()
-	for k, v := range c.options {
-		options[k] = v
-	}
-	c.mu.RUnlock()
+	options := c.optionsSnapshot()
 
 	if oastDomain, err := c.GetOASTDomain(); err == nil && oastDomain != "" {
 		options["oast_domain"] = oastDomain
@@ -133,31 +191,170 @@ func (c *Controller) ExecutePOC(pocName, target string, mode string) (*api.Outpu
 		options["oast_url"] = oastURL
 	}
 
-	var output *api.Output
-	var err error
+	start := time.Now()
+	output, err := c.executePOC(ctx, poc, pocName, mode, target, options)
+
+	result := newResult(ctx, poc, pocName, target, mode, output, err)
+	result.SetTimes(start, time.Now())
 
-	switch mode {
-	case "verify":
-		output, err = poc.Verify(target, options)
-	case "attack":
-		output, err = poc.Attack(target, options)
-	case "shell":
-		output, err = poc.Shell(target, options)
+	c.mu.Lock()
+	c.results = append(c.results, result)
+	c.mu.Unlock()
+
+	c.notifyPlugins(result)
+
+	if err != nil {
+		return nil, fmt.Errorf("POC execution failed: %w", err)
+	}
+
+	return output, nil
+}
+
+// executePOC runs poc, or the workflow it is, in mode. A panic of the POC is
+// returned as an error, so that the run is recorded like any failed one.
+func (c *Controller) executePOC(ctx context.Context, poc api.POCBase, pocName, mode, target string, options map[string]interface{}) (output *api.Output, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			output = nil
+			err = fmt.Errorf("panic in POC '%s': %v", pocName, r)
+		}
+	}()
+
+	if workflow, ok := poc.(*registry.WorkflowWrapper); ok {
+		return workflow.Run(ctx, mode, target, options, c.runPOC)
+	}
+	return c.runPOC(ctx, poc, mode, target, options)
+}
+
+// newResult describes the outcome of a run of poc: the output it produced,
+// or the error that stopped it.
+func newResult(ctx context.Context, poc api.POCBase, pocName, target, mode string, output *api.Output, err error) *api.Result {
+	result := &api.Result{
+		SchemaVersion: api.ResultSchemaVersion,
+		Target:        target,
+		POC:           pocName,
+		Name:          poc.GetName(),
+		Mode:          mode,
+	}
+	if provider, ok := poc.(api.InfoProvider); ok {
+		result.Severity = provider.GetInfo().Severity
+	}
+
+	switch {
+	case err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Status = api.StatusTimeout
+		result.Message = context.Cause(ctx).Error()
+	case err != nil && ctx.Err() != nil:
+		result.Status = api.StatusSkipped
+		result.Message = context.Cause(ctx).Error()
+	case err != nil:
+		result.Status = api.StatusError
+		result.Message = err.Error()
+	case output == nil:
+		result.Status = api.StatusError
+		result.Message = "POC returned no output"
+	case output.Success:
+		result.Status = api.StatusVulnerable
 	default:
-		return nil, fmt.Errorf("unsupported mode: %s", mode)
+		result.Status = api.StatusNotVulnerable
 	}
 
+	if err == nil && output != nil {
+		if result.Message == "" {
+			result.Message = output.Message
+		}
+		result.Extracted = output.Extracted()
+		result.Evidence = output.Evidence
+		result.Data = output.Data
+	}
+
+	return result
+}
+
+// runPOC resolves the declared options of poc and runs it in mode. It is
+// also the step runner of workflows, whose templates thus get the same
+// option handling and shell support as a POC run on its own.
+func (c *Controller) runPOC(ctx context.Context, poc api.POCBase, mode, target string, options map[string]interface{}) (*api.Output, error) {
+	options, err := api.ResolveOptions(poc.GetOptions(), options)
 	if err != nil {
-		return nil, fmt.Errorf("POC execution failed: %w", err)
+		return nil, err
 	}
 
-	c.mu.Lock()
-	c.results = append(c.results, output)
-	c.mu.Unlock()
+	if mode == "shell" {
+		return c.executeShell(ctx, poc, target, options)
+	}
+	return registry.Call(ctx, poc, mode, target, options)
+}
+
+// SetConnectBack sets the address targets connect back to in shell mode.
+func (c *Controller) SetConnectBack(host, port string) {
+	if host != "" {
+		c.SetOption("lhost", host)
+	}
+	if port != "" {
+		c.SetOption("lport", port)
+	}
+}
 
-	c.notifyPlugins(output)
+// RateLimit returns the request limits set in the RateLimit config section:
+// "rate" and "host_rate" in requests per second, "host_connections", and
+// "delay" in seconds, fixed or as a range.
+func (c *Controller) RateLimit() (*request.LimiterConfig, error) {
+	limits := &request.LimiterConfig{}
 
-	return output, nil
+	if value, ok := c.config.Get("RateLimit", "rate"); ok && value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit '%s'", value)
+		}
+		limits.RequestsPerSecond = rate
+	}
+	if value, ok := c.config.Get("RateLimit", "host_rate"); ok && value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host rate limit '%s'", value)
+		}
+		limits.HostRequestsPerSecond = rate
+	}
+	if value, ok := c.config.Get("RateLimit", "host_connections"); ok && value != "" {
+		conns, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host connections '%s'", value)
+		}
+		limits.MaxHostConnections = conns
+	}
+	if value, ok := c.config.Get("RateLimit", "delay"); ok {
+		limits.Delay = value
+	}
+
+	return limits, nil
+}
+
+// SetRateLimit makes every outbound request, of POCs and modules alike,
+// wait on limits.
+func (c *Controller) SetRateLimit(limits *request.LimiterConfig) error {
+	limiter, err := request.NewLimiter(limits)
+	if err != nil {
+		return fmt.Errorf("invalid rate limit: %w", err)
+	}
+	request.SetDefaultLimiter(limiter)
+	return nil
+}
+
+// SetResultFile records every result to path as JSON, appending to the
+// results the file holds with appendMode.
+func (c *Controller) SetResultFile(path string, appendMode bool) error {
+	plugin, err := c.pluginMgr.GetResultPlugin("file_record")
+	if err != nil {
+		return err
+	}
+
+	recorder, ok := plugin.(interface{ Open(string, bool) error })
+	if !ok {
+		return fmt.Errorf("result plugin file_record cannot record to a file")
+	}
+
+	return recorder.Open(path, appendMode)
 }
 
 func (c *Controller) SearchTargets(searcherName, query string) ([]string, error) {
@@ -178,22 +375,99 @@ func (c *Controller) SearchTargets(searcherName, query string) ([]string, error)
 	return targets, nil
 }
 
-func (c *Controller) GetResults() []*api.Output {
+// FingerprintTarget detects the technologies target runs.
+func (c *Controller) FingerprintTarget(ctx context.Context, target string) ([]interfaces.Technology, error) {
+	fingerprinter, ok := c.moduleMgr.GetFingerprinter("fingerprint")
+	if !ok {
+		return nil, fmt.Errorf("fingerprint module not found")
+	}
+
+	c.fingerprintMu.Lock()
+	if !fingerprinter.IsAvailable() {
+		if err := fingerprinter.Init(); err != nil {
+			c.fingerprintMu.Unlock()
+			return nil, fmt.Errorf("failed to initialize fingerprint module: %w", err)
+		}
+	}
+	c.fingerprintMu.Unlock()
+
+	return fingerprinter.Fingerprint(ctx, target)
+}
+
+// SelectPOCs filters names down to the POCs that apply to one of
+// technologies: those whose AppName or one of whose tags names the
+// technology, or the product of its CPE.
+func (c *Controller) SelectPOCs(names []string, technologies []interfaces.Technology) []string {
+	detected := make(map[string]bool)
+	for _, tech := range technologies {
+		detected[normalizeProduct(tech.Name)] = true
+		if fields := strings.Split(tech.CPE, ":"); len(fields) > 4 {
+			detected[normalizeProduct(fields[4])] = true
+		}
+	}
+
+	var selected []string
+	for _, name := range names {
+		poc, exists := registry.Get(name)
+		if !exists {
+			continue
+		}
+
+		keys := []string{poc.GetAppName()}
+		if provider, ok := poc.(api.InfoProvider); ok {
+			keys = append(keys, provider.GetInfo().Tags...)
+		}
+
+		for _, key := range keys {
+			if key != "" && detected[normalizeProduct(key)] {
+				selected = append(selected, name)
+				break
+			}
+		}
+	}
+
+	return selected
+}
+
+// normalizeProduct folds case and punctuation, so that "Apache Tomcat",
+// "apache_tomcat" and "apache-tomcat" compare equal.
+func normalizeProduct(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		default:
+			return -1
+		}
+	}, name)
+}
+
+// GetResults returns the results recorded so far, in the order the runs
+// finished.
+func (c *Controller) GetResults() []*api.Result {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.results
+
+	results := make([]*api.Result, len(c.results))
+	copy(results, c.results)
+	return results
 }
 
 func (c *Controller) ClearResults() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.results = make([]*api.Output, 0)
+	c.results = make([]*api.Result, 0)
 }
 
-func (c *Controller) notifyPlugins(output *api.Output) {
+func (c *Controller) notifyPlugins(result *api.Result) {
+	c.pluginMu.Lock()
+	defer c.pluginMu.Unlock()
+
 	resultPlugins := c.pluginMgr.GetResultPlugins()
 	for _, plugin := range resultPlugins {
-		plugin.Handle(output)
+		plugin.AddResult(result)
 	}
 }
 
@@ -250,6 +524,8 @@ func (c *Controller) Shutdown() error {
 	}
 
 	c.ClearResults()
+	request.SetDefaultLimiter(nil)
+	yamlpoc.ClearSharedSessions()
 
 	return nil
 }
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/seaung/pocsuite-go/api"
+)
+
+func TestNewResult(t *testing.T) {
+	poc := &fakePOC{name: "result-poc", severity: "critical"}
+
+	deadline, cancelDeadline := context.WithTimeoutCause(context.Background(), time.Nanosecond, errors.New("target timed out after 5s"))
+	defer cancelDeadline()
+	<-deadline.Done()
+
+	plainDeadline, cancelPlainDeadline := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancelPlainDeadline()
+	<-plainDeadline.Done()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	stopped, stop := context.WithCancelCause(context.Background())
+	stop(errors.New("scan interrupted"))
+
+	data := map[string]interface{}{
+		"match": map[string]interface{}{"Extracted": map[string]interface{}{"version": "1.0"}},
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		output  *api.Output
+		err     error
+		status  api.Status
+		message string
+	}{
+		{"deadline with cause", deadline, nil, context.DeadlineExceeded, api.StatusTimeout, "target timed out after 5s"},
+		{"deadline", plainDeadline, nil, errors.New("i/o timeout"), api.StatusTimeout, "context deadline exceeded"},
+		{"cancelled", cancelled, nil, context.Canceled, api.StatusSkipped, "context canceled"},
+		{"cancelled with cause", stopped, nil, context.Canceled, api.StatusSkipped, "scan interrupted"},
+		{"cancelled after success", cancelled, &api.Output{Success: true, Message: "found"}, nil, api.StatusVulnerable, "found"},
+		{"error", context.Background(), &api.Output{Message: "partial"}, errors.New("connection refused"), api.StatusError, "connection refused"},
+		{"no output", context.Background(), nil, nil, api.StatusError, "POC returned no output"},
+		{"vulnerable", context.Background(), &api.Output{Success: true, Message: "found", Data: data}, nil, api.StatusVulnerable, "found"},
+		{"not vulnerable", context.Background(), &api.Output{Message: "not found"}, nil, api.StatusNotVulnerable, "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := newResult(tt.ctx, poc, "result-poc.yaml", "http://a", "verify", tt.output, tt.err)
+
+			if result.Status != tt.status || result.Message != tt.message {
+				t.Errorf("Expected %s %q, got %s %q", tt.status, tt.message, result.Status, result.Message)
+			}
+			if result.SchemaVersion != api.ResultSchemaVersion || result.Target != "http://a" || result.POC != "result-poc.yaml" ||
+				result.Name != "result-poc" || result.Mode != "verify" || result.Severity != "critical" {
+				t.Errorf("Unexpected result fields: %+v", result)
+			}
+
+			if tt.err == nil && tt.output != nil {
+				if !reflect.DeepEqual(result.Data, tt.output.Data) || !reflect.DeepEqual(result.Extracted, tt.output.Extracted()) {
+					t.Errorf("Expected the output data, got %+v", result)
+				}
+			} else if result.Data != nil || result.Extracted != nil {
+				t.Errorf("Expected no data without a successful run, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestNewResultSeverity(t *testing.T) {
+	tests := []struct {
+		name string
+		poc  api.POCBase
+		want string
+	}{
+		{"from info", &fakePOC{name: "high", severity: "high"}, "high"},
+		{"empty info", &fakePOC{name: "unset"}, ""},
+		// The embedded interface hides GetInfo.
+		{"no info", struct{ api.POCBase }{&fakePOC{name: "plain", severity: "high"}}, ""},
+	}
+
+	for _, tt := range tests {
+		result := newResult(context.Background(), tt.poc, tt.name, "http://a", "verify", &api.Output{}, nil)
+		if result.Severity != tt.want {
+			t.Errorf("%s: expected severity %q, got %q", tt.name, tt.want, result.Severity)
+		}
+	}
+}
+
+func TestExecutePOCTimings(t *testing.T) {
+	registerFakePOC(t, &fakePOC{name: "timed-poc", run: after(succeed)})
+
+	controller, report := newTestController(t)
+	if _, err := controller.ExecutePOCContext(context.Background(), "timed-poc", "30ms", "verify"); err != nil {
+		t.Fatalf("Failed to execute POC: %v", err)
+	}
+
+	results := report.GetResults()
+	if len(results) != 1 {
+		t.Fatalf("Expected one result, got %d", len(results))
+	}
+	result := results[0]
+	if result.DurationMS < 30 || result.DurationMS != result.Duration().Milliseconds() {
+		t.Errorf("Expected a duration of at least 30ms, got %dms (%v)", result.DurationMS, result.Duration())
+	}
+}
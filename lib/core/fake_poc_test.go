@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/seaung/pocsuite-go/api"
+	"github.com/seaung/pocsuite-go/modules/manager"
+	"github.com/seaung/pocsuite-go/modules/plugins"
+	"github.com/seaung/pocsuite-go/registry"
+)
+
+// fakePOC is a POC whose runs are done by run.
+type fakePOC struct {
+	name     string
+	severity string
+	run      func(ctx context.Context, target string) (*api.Output, error)
+}
+
+func (p *fakePOC) GetVulID() string                   { return "" }
+func (p *fakePOC) GetVersion() string                 { return "" }
+func (p *fakePOC) GetAuthor() string                  { return "" }
+func (p *fakePOC) GetVulDate() string                 { return "" }
+func (p *fakePOC) GetCreateDate() string              { return "" }
+func (p *fakePOC) GetUpdateDate() string              { return "" }
+func (p *fakePOC) GetReferences() []string            { return nil }
+func (p *fakePOC) GetName() string                    { return p.name }
+func (p *fakePOC) GetAppPowerLink() string            { return "" }
+func (p *fakePOC) GetAppName() string                 { return "" }
+func (p *fakePOC) GetAppVersion() string              { return "" }
+func (p *fakePOC) GetVulType() string                 { return "" }
+func (p *fakePOC) GetCategory() string                { return "" }
+func (p *fakePOC) GetSamples() []string               { return nil }
+func (p *fakePOC) GetInstallRequires() []string       { return nil }
+func (p *fakePOC) GetDesc() string                    { return "" }
+func (p *fakePOC) GetPocDesc() string                 { return "" }
+func (p *fakePOC) GetOptions() map[string]interface{} { return nil }
+func (p *fakePOC) GetInfo() *api.POCInfo              { return &api.POCInfo{Name: p.name, Severity: p.severity} }
+
+func (p *fakePOC) Verify(target string, options map[string]interface{}) (*api.Output, error) {
+	return p.VerifyContext(context.Background(), target, options)
+}
+
+func (p *fakePOC) Attack(target string, options map[string]interface{}) (*api.Output, error) {
+	return p.AttackContext(context.Background(), target, options)
+}
+
+func (p *fakePOC) Shell(target string, options map[string]interface{}) (*api.Output, error) {
+	return p.ShellContext(context.Background(), target, options)
+}
+
+func (p *fakePOC) VerifyContext(ctx context.Context, target string, options map[string]interface{}) (*api.Output, error) {
+	return p.run(ctx, target)
+}
+
+func (p *fakePOC) AttackContext(ctx context.Context, target string, options map[string]interface{}) (*api.Output, error) {
+	return p.run(ctx, target)
+}
+
+func (p *fakePOC) ShellContext(ctx context.Context, target string, options map[string]interface{}) (*api.Output, error) {
+	return p.run(ctx, target)
+}
+
+// registerFakePOC registers poc under its name for the duration of the test.
+func registerFakePOC(t *testing.T, poc *fakePOC) {
+	t.Helper()
+
+	if err := registry.Register(poc.name, poc); err != nil {
+		t.Fatalf("Failed to register %s: %v", poc.name, err)
+	}
+	t.Cleanup(func() {
+		registry.Unregister(poc.name)
+	})
+}
+
+// newTestController returns a controller without modules, whose results
+// are also handed to the returned result plugin.
+func newTestController(t *testing.T) (*Controller, plugins.ResultPlugin) {
+	t.Helper()
+
+	report := plugins.NewHTMLReportPlugin()
+	pluginMgr := plugins.NewPluginManager()
+	if err := pluginMgr.RegisterPlugin(report); err != nil {
+		t.Fatalf("Failed to register plugin: %v", err)
+	}
+
+	return &Controller{
+		moduleMgr:     manager.NewModuleManager(),
+		pluginMgr:     pluginMgr,
+		pocLoader:     NewPOCLoader(),
+		options:       make(map[string]interface{}),
+		shellSessions: make(map[interface{}]bool),
+	}, report
+}
+
+func succeed(ctx context.Context, target string) (*api.Output, error) {
+	output := api.NewOutput()
+	output.SuccessOutput(map[string]interface{}{"Target": target})
+	return output, nil
+}
+
+func fail(ctx context.Context, target string) (*api.Output, error) {
+	output := api.NewOutput()
+	output.FailOutput("not vulnerable")
+	return output, nil
+}
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,13 +12,17 @@ import (
 	"github.com/seaung/pocsuite-go/yamlpoc"
 )
 
+// POCLoader tracks the POCs it registered, keyed by ID, along with the
+// file each one was loaded from and the digest of its content.
 type POCLoader struct {
-	loadedPOCs map[string]bool
+	loadedPOCs map[string]string
+	digests    map[string]string
 }
 
 func NewPOCLoader() *POCLoader {
 	return &POCLoader{
-		loadedPOCs: make(map[string]bool),
+		loadedPOCs: make(map[string]string),
+		digests:    make(map[string]string),
 	}
 }
 
@@ -25,25 +31,89 @@ func (pl *POCLoader) LoadFromFile(pocPath string) (string, error) {
 		return "", fmt.Errorf("POC file does not exist: %s", pocPath)
 	}
 
-	yamlPOC, err := yamlpoc.ParseFile(pocPath)
+	data, err := os.ReadFile(pocPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read POC: %w", err)
+	}
+
+	if yamlpoc.IsWorkflow(data) {
+		return pl.loadWorkflow(pocPath, data)
+	}
+
+	yamlPOC, err := parsePOCFile(pocPath, data)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse POC: %w", err)
 	}
 
-	pocName := filepath.Base(pocPath)
-	pocName = strings.TrimSuffix(pocName, filepath.Ext(pocName))
+	return pl.register(pocID(yamlPOC.ID, pocPath), pocPath, data, func(name string) error {
+		return registry.RegisterYAMLPOC(name, yamlPOC)
+	})
+}
+
+// loadWorkflow registers the workflow at path, whose content is data.
+// Templates it names by file are loaded first, relative to the workflow,
+// and then referred to by ID.
+func (pl *POCLoader) loadWorkflow(path string, data []byte) (string, error) {
+	workflow, err := yamlpoc.ParseWorkflowFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse workflow: %w", err)
+	}
+
+	err = workflow.Walk(func(entry string, t *yamlpoc.WorkflowTemplate) error {
+		if !t.IsFile() {
+			return nil
+		}
+
+		file := t.Template
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
 
-	if _, exists := pl.loadedPOCs[pocName]; exists {
-		return pocName, fmt.Errorf("POC '%s' is already loaded", pocName)
+		name, err := pl.LoadFromFile(file)
+		if err != nil && name == "" {
+			return fmt.Errorf("%s: %w", entry, err)
+		}
+		t.Template = name
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to load workflow templates: %w", err)
 	}
 
-	if err := registry.RegisterYAMLPOC(pocName, yamlPOC); err != nil {
+	return pl.register(pocID(workflow.ID, path), path, data, func(name string) error {
+		return registry.RegisterWorkflow(name, workflow)
+	})
+}
+
+// register records name as loaded from path, whose content is data, once
+// register succeeds. A second load of the same file returns its name along
+// with the error.
+func (pl *POCLoader) register(name, path string, data []byte, register func(name string) error) (string, error) {
+	if loadedFrom, exists := pl.loadedPOCs[name]; exists {
+		if sameFile(loadedFrom, path) {
+			return name, fmt.Errorf("POC '%s' is already loaded", name)
+		}
+		return "", fmt.Errorf("duplicate POC id '%s' in %s, already loaded from %s", name, path, loadedFrom)
+	}
+
+	if err := register(name); err != nil {
 		return "", fmt.Errorf("failed to register POC: %w", err)
 	}
 
-	pl.loadedPOCs[pocName] = true
+	pl.loadedPOCs[name] = path
+	digest := sha256.Sum256(data)
+	pl.digests[name] = hex.EncodeToString(digest[:])
 
-	return pocName, nil
+	return name, nil
+}
+
+// pocID falls back to the file name for templates without an id.
+func pocID(id, path string) string {
+	if id != "" {
+		return id
+	}
+	name := filepath.Base(path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
 }
 
 func (pl *POCLoader) LoadFromDir(dir string) ([]string, error) {
@@ -67,6 +137,11 @@ func (pl *POCLoader) LoadFromDir(dir string) ([]string, error) {
 			return nil
 		}
 
+		// Templates a workflow names by file were loaded along with it.
+		if pl.isLoadedFile(path) {
+			return nil
+		}
+
 		pocName, err := pl.LoadFromFile(path)
 		if err != nil {
 			fmt.Printf("Warning: Failed to load POC from %s: %v\n", path, err)
@@ -122,6 +197,7 @@ func (pl *POCLoader) Unload(pocName string) error {
 	registry.Unregister(pocName)
 
 	delete(pl.loadedPOCs, pocName)
+	delete(pl.digests, pocName)
 
 	return nil
 }
@@ -130,9 +206,42 @@ func (pl *POCLoader) Clear() {
 	for pocName := range pl.loadedPOCs {
 		registry.Unregister(pocName)
 	}
-	pl.loadedPOCs = make(map[string]bool)
+	pl.loadedPOCs = make(map[string]string)
+	pl.digests = make(map[string]string)
+}
+
+// Digest returns the SHA-256 of the file pocName was loaded from, or ""
+// when the loader did not load it.
+func (pl *POCLoader) Digest(pocName string) string {
+	return pl.digests[pocName]
 }
 
 func (pl *POCLoader) Count() int {
 	return len(pl.loadedPOCs)
 }
+
+func (pl *POCLoader) isLoadedFile(path string) bool {
+	for _, loadedFrom := range pl.loadedPOCs {
+		if sameFile(loadedFrom, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return absA == absB
+}
+
+// parsePOCFile parses a native template, or converts a nuclei one.
+func parsePOCFile(pocPath string, data []byte) (*yamlpoc.YAMLPOC, error) {
+	if yamlpoc.IsNucleiTemplate(data) {
+		return yamlpoc.ParseNucleiFile(pocPath)
+	}
+	return yamlpoc.ParseFile(pocPath)
+}
@@ -0,0 +1,373 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/seaung/pocsuite-go/api"
+	"github.com/seaung/pocsuite-go/modules/interfaces"
+	"github.com/seaung/pocsuite-go/yamlpoc"
+)
+
+const defaultThreads = 10
+
+// Task is one POC to run against one target.
+type Task struct {
+	Target string `json:"target"`
+	POC    string `json:"poc"`
+}
+
+// ScanResult is the outcome of a task. Index is the task's position in the
+// scan, which is also the order results are handed out in.
+type ScanResult struct {
+	Task
+	Index    int
+	Output   *api.Output
+	Err      error
+	Duration time.Duration
+}
+
+// ScanSummary counts the outcomes of a scan. A task succeeded when its POC
+// reported success, failed when it ran without success, and errored when
+// it could not run, panicked or was stopped. Tasks that never started
+// because the scan was cancelled are skipped.
+type ScanSummary struct {
+	Targets   int
+	POCs      int
+	Tasks     int
+	Succeeded int
+	Failed    int
+	Errors    int
+	Skipped   int
+	Duration  time.Duration
+}
+
+// Scanner runs tasks through the controller on a pool of workers.
+type Scanner struct {
+	controller    *Controller
+	threads       int
+	mode          string
+	pocTimeout    time.Duration
+	targetTimeout time.Duration
+	checkpoint    *Checkpoint
+}
+
+func NewScanner(controller *Controller, threads int, mode string) *Scanner {
+	if threads < 1 {
+		threads = defaultThreads
+	}
+	return &Scanner{
+		controller: controller,
+		threads:    threads,
+		mode:       mode,
+	}
+}
+
+// SetTimeouts bounds how long a single POC may run, and how long all the
+// POCs of a target may take together, counted from the start of its first
+// task. Zero means no limit.
+func (s *Scanner) SetTimeouts(pocTimeout, targetTimeout time.Duration) {
+	s.pocTimeout = pocTimeout
+	s.targetTimeout = targetTimeout
+}
+
+// SetCheckpoint makes the scanner record every task it completes in cp.
+// Tasks stopped because the scan was cancelled are not complete.
+func (s *Scanner) SetCheckpoint(cp *Checkpoint) {
+	s.checkpoint = cp
+}
+
+// Tasks pairs every target with every POC, target by target.
+func Tasks(targets, pocs []string) []Task {
+	tasks := make([]Task, 0, len(targets)*len(pocs))
+	for _, target := range targets {
+		for _, poc := range pocs {
+			tasks = append(tasks, Task{Target: target, POC: poc})
+		}
+	}
+	return tasks
+}
+
+// Run fans tasks out to the workers and passes every result to handle in
+// task order, as soon as the results before it are in. handle is never
+// called concurrently. A panicking POC is reported as an error of its task
+// and does not take its worker down. Once ctx is done no further task is
+// started, the running ones are stopped, and Run returns when they have
+// been handled.
+func (s *Scanner) Run(ctx context.Context, tasks []Task, handle func(*ScanResult)) *ScanSummary {
+	start := time.Now()
+
+	targetCtx := newTargetContexts(ctx, s.targetTimeout)
+	defer targetCtx.cancel()
+
+	summary := &ScanSummary{Tasks: len(tasks)}
+	targets := make(map[string]bool)
+	pocs := make(map[string]bool)
+	for _, task := range tasks {
+		targets[task.Target] = true
+		pocs[task.POC] = true
+	}
+	summary.Targets = len(targets)
+	summary.POCs = len(pocs)
+
+	sessions := newTargetSessions(tasks)
+
+	run := func(index int) *ScanResult {
+		task := tasks[index]
+		return s.runTask(sessions.get(targetCtx.get(task.Target), task.Target), index, task)
+	}
+
+	handled := runOrdered(ctx, s.threads, len(tasks), run, func(ready *ScanResult) {
+		switch {
+		case ready.Err != nil:
+			summary.Errors++
+		case ready.Output != nil && ready.Output.Success:
+			summary.Succeeded++
+		default:
+			summary.Failed++
+		}
+
+		if handle != nil {
+			handle(ready)
+		}
+		sessions.done(ready.Target)
+
+		if s.checkpoint != nil && (ready.Err == nil || ctx.Err() == nil) {
+			s.checkpoint.Complete(ready.Task)
+		}
+	})
+
+	summary.Skipped = summary.Tasks - handled
+	summary.Duration = time.Since(start)
+	return summary
+}
+
+// FingerprintResult is what fingerprinting a target found.
+type FingerprintResult struct {
+	Target       string
+	Technologies []interfaces.Technology
+	Err          error
+}
+
+// Fingerprint detects the technologies of targets on the workers, bounded
+// by the target timeout, and passes every result to handle in target
+// order, the way Run does. Once ctx is done no further target is
+// fingerprinted.
+func (s *Scanner) Fingerprint(ctx context.Context, targets []string, handle func(*FingerprintResult)) {
+	targetCtx := newTargetContexts(ctx, s.targetTimeout)
+	defer targetCtx.cancel()
+
+	run := func(index int) *FingerprintResult {
+		target := targets[index]
+		return s.fingerprint(targetCtx.get(target), target)
+	}
+
+	runOrdered(ctx, s.threads, len(targets), run, func(ready *FingerprintResult) {
+		if handle != nil {
+			handle(ready)
+		}
+	})
+}
+
+func (s *Scanner) fingerprint(ctx context.Context, target string) (result *FingerprintResult) {
+	result = &FingerprintResult{Target: target}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result.Technologies = nil
+			result.Err = fmt.Errorf("panic while fingerprinting '%s': %v", target, r)
+		}
+	}()
+
+	result.Technologies, result.Err = s.controller.FingerprintTarget(ctx, target)
+	if result.Err != nil && ctx.Err() != nil {
+		result.Err = context.Cause(ctx)
+	}
+	return result
+}
+
+// runOrdered runs work for indexes 0 to count-1 on threads workers, and
+// passes the results to handle in index order, as soon as the results
+// before them are in. handle is never called concurrently. Once ctx is
+// done no further index is started; runOrdered returns when the running
+// ones have been handled, with the number of results handled.
+func runOrdered[R any](ctx context.Context, threads, count int, work func(index int) R, handle func(R)) int {
+	type indexed struct {
+		index  int
+		result R
+	}
+
+	jobs := make(chan int)
+	results := make(chan indexed, threads)
+
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				results <- indexed{index: index, result: work(index)}
+			}
+		}()
+	}
+
+	// Indexes are handed out in order, so the ones started when ctx is
+	// done are the first and their results come without gaps.
+	go func() {
+		defer func() {
+			close(jobs)
+			wg.Wait()
+			close(results)
+		}()
+		for index := 0; index < count; index++ {
+			// select picks at random when a worker is also free.
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case jobs <- index:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Results arrive in completion order and are held back until every
+	// earlier index has been handled.
+	pending := make(map[int]R)
+	next := 0
+	for r := range results {
+		pending[r.index] = r.result
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			handle(ready)
+		}
+	}
+
+	return next
+}
+
+func (s *Scanner) runTask(ctx context.Context, index int, task Task) (result *ScanResult) {
+	start := time.Now()
+	result = &ScanResult{Task: task, Index: index}
+
+	if s.pocTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, s.pocTimeout, fmt.Errorf("POC timed out after %s", s.pocTimeout))
+		defer cancel()
+	}
+
+	// The controller records a panic of the POC itself as a failed run;
+	// this is for anything else, such as a result plugin, that panics.
+	defer func() {
+		if r := recover(); r != nil {
+			result.Output = nil
+			result.Err = fmt.Errorf("panic running POC '%s': %v", task.POC, r)
+		}
+		result.Duration = time.Since(start)
+	}()
+
+	result.Output, result.Err = s.controller.ExecutePOCContext(ctx, task.POC, task.Target, s.mode)
+	if result.Err != nil && ctx.Err() != nil {
+		result.Err = context.Cause(ctx)
+	}
+	return result
+}
+
+// targetContexts hands out one context per target, whose deadline starts
+// with the first task of the target.
+type targetContexts struct {
+	parent  context.Context
+	timeout time.Duration
+
+	mu      sync.Mutex
+	ctxs    map[string]context.Context
+	cancels []context.CancelFunc
+}
+
+func newTargetContexts(parent context.Context, timeout time.Duration) *targetContexts {
+	return &targetContexts{
+		parent:  parent,
+		timeout: timeout,
+		ctxs:    make(map[string]context.Context),
+	}
+}
+
+func (t *targetContexts) get(target string) context.Context {
+	if t.timeout <= 0 {
+		return t.parent
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ctx, ok := t.ctxs[target]
+	if !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(t.parent, t.timeout, fmt.Errorf("target timed out after %s", t.timeout))
+		t.ctxs[target] = ctx
+		t.cancels = append(t.cancels, cancel)
+	}
+	return ctx
+}
+
+func (t *targetContexts) cancel() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, cancel := range t.cancels {
+		cancel()
+	}
+}
+
+// targetSessions gives the shared-session templates run against a target
+// cookies of their own, which are dropped once the last task of the target
+// has been handled, rather than kept for the whole scan.
+type targetSessions struct {
+	mu        sync.Mutex
+	ctxs      map[string]context.Context
+	remaining map[string]int
+}
+
+func newTargetSessions(tasks []Task) *targetSessions {
+	remaining := make(map[string]int)
+	for _, task := range tasks {
+		remaining[task.Target]++
+	}
+	return &targetSessions{
+		ctxs:      make(map[string]context.Context),
+		remaining: remaining,
+	}
+}
+
+// get returns ctx with the session manager of target.
+func (t *targetSessions) get(ctx context.Context, target string) context.Context {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sessionCtx, ok := t.ctxs[target]
+	if !ok {
+		sessionCtx = yamlpoc.WithSharedSessions(ctx)
+		t.ctxs[target] = sessionCtx
+	}
+	return sessionCtx
+}
+
+// done records that a task of target was handled.
+func (t *targetSessions) done(target string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.remaining[target]--
+	if t.remaining[target] <= 0 {
+		delete(t.ctxs, target)
+		delete(t.remaining, target)
+	}
+}
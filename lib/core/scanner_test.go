@@ -0,0 +1,401 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/seaung/pocsuite-go/api"
+	"github.com/seaung/pocsuite-go/modules/interfaces"
+	"github.com/seaung/pocsuite-go/modules/manager"
+)
+
+// fakeFingerprinter detects a technology named after each target, after
+// the delay set for the target.
+type fakeFingerprinter struct {
+	delays map[string]time.Duration
+
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (f *fakeFingerprinter) Name() string      { return "fingerprint" }
+func (f *fakeFingerprinter) Init() error       { return nil }
+func (f *fakeFingerprinter) IsAvailable() bool { return true }
+
+func (f *fakeFingerprinter) Fingerprint(ctx context.Context, target string) ([]interfaces.Technology, error) {
+	f.mu.Lock()
+	f.running++
+	f.peak = max(f.peak, f.running)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.running--
+		f.mu.Unlock()
+	}()
+
+	if target == "panic" {
+		panic("boom")
+	}
+
+	select {
+	case <-time.After(f.delays[target]):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return []interfaces.Technology{{Name: "tech-" + target}}, nil
+}
+
+func newFingerprintController(t *testing.T, fingerprinter *fakeFingerprinter) *Controller {
+	t.Helper()
+
+	moduleMgr := manager.NewModuleManager()
+	if err := moduleMgr.Register(fingerprinter); err != nil {
+		t.Fatalf("Failed to register fingerprinter: %v", err)
+	}
+	return &Controller{moduleMgr: moduleMgr}
+}
+
+func TestScannerFingerprint(t *testing.T) {
+	fingerprinter := &fakeFingerprinter{delays: map[string]time.Duration{
+		"a": 150 * time.Millisecond,
+		"b": 10 * time.Millisecond,
+		"c": 80 * time.Millisecond,
+	}}
+	scanner := NewScanner(newFingerprintController(t, fingerprinter), 3, "verify")
+
+	var targets []string
+	start := time.Now()
+	scanner.Fingerprint(context.Background(), []string{"a", "b", "panic", "c"}, func(result *FingerprintResult) {
+		targets = append(targets, result.Target)
+		switch {
+		case result.Target == "panic":
+			if result.Err == nil {
+				t.Error("Expected a panicking fingerprinter to be reported as an error")
+			}
+		case result.Err != nil:
+			t.Errorf("Failed to fingerprint %s: %v", result.Target, result.Err)
+		case len(result.Technologies) != 1 || result.Technologies[0].Name != "tech-"+result.Target:
+			t.Errorf("Expected the technology of %s, got %v", result.Target, result.Technologies)
+		}
+	})
+	elapsed := time.Since(start)
+
+	if fmt.Sprint(targets) != "[a b panic c]" {
+		t.Errorf("Expected results in target order, got %v", targets)
+	}
+	if fingerprinter.peak < 2 || fingerprinter.peak > 3 {
+		t.Errorf("Expected targets fingerprinted concurrently on 3 workers, peak was %d", fingerprinter.peak)
+	}
+	if elapsed > 220*time.Millisecond {
+		t.Errorf("Expected targets fingerprinted concurrently, took %v", elapsed)
+	}
+}
+
+func TestScannerFingerprintTimeout(t *testing.T) {
+	fingerprinter := &fakeFingerprinter{delays: map[string]time.Duration{"slow": time.Minute}}
+	scanner := NewScanner(newFingerprintController(t, fingerprinter), 1, "verify")
+	scanner.SetTimeouts(0, 50*time.Millisecond)
+
+	var results []*FingerprintResult
+	scanner.Fingerprint(context.Background(), []string{"slow"}, func(result *FingerprintResult) {
+		results = append(results, result)
+	})
+
+	if len(results) != 1 || results[0].Err == nil {
+		t.Fatalf("Expected the slow target to time out, got %v", results)
+	}
+	if got := results[0].Err.Error(); got != "target timed out after 50ms" {
+		t.Errorf("Expected the target timeout as the error, got %q", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	scanner.Fingerprint(ctx, []string{"slow"}, func(*FingerprintResult) {
+		called = true
+	})
+	if called {
+		t.Error("Expected no target to be fingerprinted once the context is done")
+	}
+}
+
+// concurrency counts the runs in progress and the most seen at once.
+type concurrency struct {
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (c *concurrency) track(run func(context.Context, string) (*api.Output, error)) func(context.Context, string) (*api.Output, error) {
+	return func(ctx context.Context, target string) (*api.Output, error) {
+		c.mu.Lock()
+		c.running++
+		c.peak = max(c.peak, c.running)
+		c.mu.Unlock()
+		defer func() {
+			c.mu.Lock()
+			c.running--
+			c.mu.Unlock()
+		}()
+		return run(ctx, target)
+	}
+}
+
+// after delays run by the delay given in the target, e.g. "30ms".
+func after(run func(context.Context, string) (*api.Output, error)) func(context.Context, string) (*api.Output, error) {
+	return func(ctx context.Context, target string) (*api.Output, error) {
+		delay, _ := time.ParseDuration(target)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return run(ctx, target)
+	}
+}
+
+func TestScannerRun(t *testing.T) {
+	tracker := &concurrency{}
+	registerFakePOC(t, &fakePOC{name: "scan-vulnerable", run: tracker.track(after(succeed))})
+	registerFakePOC(t, &fakePOC{name: "scan-safe", run: tracker.track(after(fail))})
+	registerFakePOC(t, &fakePOC{name: "scan-error", run: tracker.track(after(func(context.Context, string) (*api.Output, error) {
+		return nil, errors.New("connection refused")
+	}))})
+	registerFakePOC(t, &fakePOC{name: "scan-panic", run: tracker.track(func(context.Context, string) (*api.Output, error) {
+		panic("boom")
+	})})
+
+	tests := []struct {
+		name    string
+		threads int
+		targets []string
+		pocs    []string
+		// want is the summary, less its duration.
+		want     ScanSummary
+		wantPeak int
+	}{
+		{
+			name:     "ordered despite completion order",
+			threads:  4,
+			targets:  []string{"80ms", "10ms", "40ms", "0s"},
+			pocs:     []string{"scan-vulnerable", "scan-safe"},
+			want:     ScanSummary{Targets: 4, POCs: 2, Tasks: 8, Succeeded: 4, Failed: 4},
+			wantPeak: 4,
+		},
+		{
+			name:     "single worker",
+			threads:  1,
+			targets:  []string{"10ms", "0s", "5ms"},
+			pocs:     []string{"scan-vulnerable"},
+			want:     ScanSummary{Targets: 3, POCs: 1, Tasks: 3, Succeeded: 3},
+			wantPeak: 1,
+		},
+		{
+			name:     "more workers than tasks",
+			threads:  10,
+			targets:  []string{"30ms", "30ms"},
+			pocs:     []string{"scan-safe"},
+			want:     ScanSummary{Targets: 1, POCs: 1, Tasks: 2, Failed: 2},
+			wantPeak: 2,
+		},
+		{
+			name:     "errors and panics are isolated",
+			threads:  2,
+			targets:  []string{"20ms", "0s"},
+			pocs:     []string{"scan-panic", "scan-vulnerable", "scan-error"},
+			want:     ScanSummary{Targets: 2, POCs: 3, Tasks: 6, Succeeded: 2, Errors: 4},
+			wantPeak: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*tracker = concurrency{}
+			controller, report := newTestController(t)
+			tasks := Tasks(tt.targets, tt.pocs)
+
+			var handled []Task
+			summary := NewScanner(controller, tt.threads, "verify").Run(context.Background(), tasks, func(result *ScanResult) {
+				if result.Index != len(handled) {
+					t.Errorf("Expected result %d, got %d", len(handled), result.Index)
+				}
+				handled = append(handled, result.Task)
+
+				switch result.POC {
+				case "scan-panic":
+					if result.Err == nil || !strings.Contains(result.Err.Error(), "boom") {
+						t.Errorf("Expected the panic as the error, got %v", result.Err)
+					}
+				case "scan-error":
+					if result.Err == nil {
+						t.Error("Expected the error of the POC")
+					}
+				default:
+					if result.Err != nil {
+						t.Errorf("Unexpected error from %s: %v", result.POC, result.Err)
+					}
+				}
+			})
+
+			if !reflect.DeepEqual(handled, tasks) {
+				t.Errorf("Expected results in task order %v, got %v", tasks, handled)
+			}
+
+			summary.Duration = 0
+			if *summary != tt.want {
+				t.Errorf("Expected summary %+v, got %+v", tt.want, *summary)
+			}
+			if tracker.peak != tt.wantPeak {
+				t.Errorf("Expected %d tasks at once, got %d", tt.wantPeak, tracker.peak)
+			}
+
+			if got := len(controller.GetResults()); got != len(tasks) {
+				t.Errorf("Expected a recorded result per task, got %d", got)
+			}
+			if got := len(report.GetResults()); got != len(tasks) {
+				t.Errorf("Expected the plugins to get a result per task, got %d", got)
+			}
+		})
+	}
+}
+
+func TestScannerRunPanicRecorded(t *testing.T) {
+	registerFakePOC(t, &fakePOC{name: "scan-panic-recorded", severity: "high", run: func(context.Context, string) (*api.Output, error) {
+		panic("index out of range")
+	}})
+
+	controller, report := newTestController(t)
+	summary := NewScanner(controller, 1, "verify").Run(context.Background(), Tasks([]string{"http://a"}, []string{"scan-panic-recorded"}), nil)
+	if summary.Errors != 1 {
+		t.Fatalf("Expected the panic to count as an error, got %+v", summary)
+	}
+
+	for source, results := range map[string][]*api.Result{
+		"controller": controller.GetResults(),
+		"plugin":     report.GetResults(),
+	} {
+		if len(results) != 1 {
+			t.Fatalf("Expected the %s to have the result of the panicking POC, got %v", source, results)
+		}
+		result := results[0]
+		if result.Status != api.StatusError || result.POC != "scan-panic-recorded" || result.Target != "http://a" || result.Severity != "high" {
+			t.Errorf("Unexpected %s result: %+v", source, result)
+		}
+		if !strings.Contains(result.Message, "index out of range") {
+			t.Errorf("Expected the panic in the %s result, got %q", source, result.Message)
+		}
+	}
+}
+
+func TestScannerRunCancel(t *testing.T) {
+	registerFakePOC(t, &fakePOC{name: "scan-cancel", run: after(succeed)})
+
+	controller, _ := newTestController(t)
+	targets := []string{"0s", "0s", "1m", "1m", "1m", "1m"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var handled []int
+	start := time.Now()
+	summary := NewScanner(controller, 2, "verify").Run(ctx, Tasks(targets, []string{"scan-cancel"}), func(result *ScanResult) {
+		handled = append(handled, result.Index)
+	})
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the scan to stop when cancelled, took %v", elapsed)
+	}
+	for i, index := range handled {
+		if index != i {
+			t.Fatalf("Expected the handled results to be the first tasks, got %v", handled)
+		}
+	}
+	if summary.Succeeded != 2 || summary.Errors != len(handled)-2 || summary.Skipped != len(targets)-len(handled) {
+		t.Errorf("Unexpected summary %+v for %d handled results", summary, len(handled))
+	}
+}
+
+func TestScannerRunSharedSessions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "admin-session", Path: "/"})
+		case "/admin":
+			if cookie, err := r.Cookie("sid"); err == nil && cookie.Value == "admin-session" {
+				w.Write([]byte("Welcome, admin"))
+			}
+		}
+	}))
+	defer server.Close()
+
+	templates := map[string]string{
+		"session-login": "/login",
+		"session-admin": "/admin",
+	}
+	dir := t.TempDir()
+	for name, path := range templates {
+		content := fmt.Sprintf(`id: %s
+info:
+  name: %s
+shared-session: true
+requests:
+  - method: GET
+    path: "{{BaseURL}}%s"
+    matchers:
+      - type: word
+        words:
+          - "Welcome, admin"
+`, name, name, path)
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write template: %v", err)
+		}
+	}
+
+	controller, _ := newTestController(t)
+	if _, err := controller.LoadPOCsFromDir(dir); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+	t.Cleanup(controller.ClearPOCs)
+
+	scan := func(pocs ...string) int {
+		scanner := NewScanner(controller, 1, "verify")
+		return scanner.Run(context.Background(), Tasks([]string{server.URL}, pocs), nil).Succeeded
+	}
+
+	if succeeded := scan("session-login", "session-admin"); succeeded != 1 {
+		t.Errorf("Expected the admin template to reuse the session of the login one, %d succeeded", succeeded)
+	}
+	if succeeded := scan("session-admin"); succeeded != 0 {
+		t.Errorf("Expected a later scan not to reuse the session, %d succeeded", succeeded)
+	}
+}
+
+func TestTargetSessions(t *testing.T) {
+	sessions := newTargetSessions(Tasks([]string{"a", "b"}, []string{"x", "y"}))
+	ctx := context.Background()
+
+	a := sessions.get(ctx, "a")
+	if sessions.get(ctx, "a") != a || sessions.get(ctx, "b") == a {
+		t.Error("Expected one context per target")
+	}
+
+	sessions.done("a")
+	if sessions.get(ctx, "a") != a {
+		t.Error("Expected the context to be kept while a task of the target is left")
+	}
+	sessions.done("a")
+	if len(sessions.ctxs) != 1 {
+		t.Errorf("Expected the context of a done target to be dropped, got %d", len(sessions.ctxs))
+	}
+}
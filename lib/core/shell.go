@@ -0,0 +1,179 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seaung/pocsuite-go/api"
+	"github.com/seaung/pocsuite-go/registry"
+)
+
+// executeShell makes sure the reverse_tcp listener is up on the connect-back
+// port, runs the POC in shell mode and waits for the target to connect back.
+// The session is reported under "ShellSession" and can then be driven with
+// the listener commands.
+//
+// Several targets may be attacked at once, so a session is only credited to
+// a target when it comes from one of the target's addresses, and never to
+// more than one task. A target that connects back from another address, e.g.
+// through NAT, is reported as not having connected.
+func (c *Controller) executeShell(ctx context.Context, poc api.POCBase, target string, options map[string]interface{}) (*api.Output, error) {
+	if optionString(options, "lhost") == "" {
+		return nil, fmt.Errorf("shell mode requires a connect-back host, pass --lhost (or set lhost in the console)")
+	}
+	lport := optionString(options, "lport")
+	if lport == "" {
+		lport = defaultConnectBackPort
+	}
+
+	// The options belong to the caller.
+	shellOptions := make(map[string]interface{}, len(options)+1)
+	for k, v := range options {
+		shellOptions[k] = v
+	}
+	shellOptions["lport"] = lport
+
+	if err := c.startConnectBackListener(lport); err != nil {
+		return nil, err
+	}
+
+	addresses, err := targetAddresses(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[interface{}]bool)
+	for _, client := range c.listenerMgr.ListClients() {
+		existing[client.Conn] = true
+	}
+
+	output, err := registry.Call(ctx, poc, "shell", target, shellOptions)
+	if err != nil || !output.Success {
+		return output, err
+	}
+
+	timeout := defaultShellTimeout
+	if seconds, err := strconv.Atoi(optionString(options, "shell_timeout")); err == nil && seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if index, address, ok := c.claimSession(existing, addresses); ok {
+			output.Data["ShellSession"] = map[string]interface{}{
+				"Client":  index,
+				"Address": address,
+			}
+			return output, nil
+		}
+
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	output.FailOutput(fmt.Sprintf("no connection back from %s on port %s within %s", target, lport, timeout))
+	return output, nil
+}
+
+// startConnectBackListener starts the reverse_tcp listener on lport unless
+// it is running already. A listener running on another port is an error,
+// as the targets would connect back where nothing listens.
+func (c *Controller) startConnectBackListener(lport string) error {
+	c.shellMu.Lock()
+	defer c.shellMu.Unlock()
+
+	reverseTCP, err := c.listenerMgr.GetListener("reverse_tcp")
+	if err != nil {
+		return fmt.Errorf("shell mode needs the reverse_tcp listener: %w", err)
+	}
+	if reverseTCP.IsAvailable() {
+		if rtcp, ok := reverseTCP.(interface{ GetListenAddress() string }); ok {
+			if _, port, err := net.SplitHostPort(rtcp.GetListenAddress()); err == nil && port != lport {
+				return fmt.Errorf("reverse_tcp listener is running on port %s, not on the connect-back port %s", port, lport)
+			}
+		}
+		return nil
+	}
+
+	c.SetOption("reverse_tcp_port", lport)
+	if err := c.StartListener("reverse_tcp"); err != nil {
+		return fmt.Errorf("failed to start reverse_tcp listener: %w", err)
+	}
+	return nil
+}
+
+// claimSession looks for a session from one of addresses that was not open
+// before the task started and no other task claimed, and claims it.
+func (c *Controller) claimSession(existing map[interface{}]bool, addresses map[string]bool) (int, string, bool) {
+	c.shellMu.Lock()
+	defer c.shellMu.Unlock()
+
+	for i, client := range c.listenerMgr.ListClients() {
+		if existing[client.Conn] || c.shellSessions[client.Conn] {
+			continue
+		}
+
+		addr, ok := client.Address.(net.Addr)
+		if !ok {
+			continue
+		}
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			continue
+		}
+		if ip := net.ParseIP(host); ip == nil || !addresses[ip.String()] {
+			continue
+		}
+
+		c.shellSessions[client.Conn] = true
+		return i, addr.String(), true
+	}
+
+	return 0, "", false
+}
+
+// targetAddresses returns the IP addresses target may connect back from.
+func targetAddresses(ctx context.Context, target string) (map[string]bool, error) {
+	host := target
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target '%s': %w", target, err)
+		}
+		host = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(target); err == nil {
+		host = h
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return map[string]bool{ip.String(): true}, nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve target '%s': %w", host, err)
+	}
+
+	addresses := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		addresses[ip.IP.String()] = true
+	}
+	return addresses, nil
+}
+
+// optionString returns option name as text, whatever type it was set with.
+func optionString(options map[string]interface{}, name string) string {
+	value, ok := options[name]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
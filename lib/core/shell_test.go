@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/seaung/pocsuite-go/api"
+	"github.com/seaung/pocsuite-go/modules/interfaces"
+	"github.com/seaung/pocsuite-go/modules/listener"
+)
+
+func TestClaimSession(t *testing.T) {
+	c := &Controller{
+		listenerMgr:   listener.New(nil),
+		shellSessions: make(map[interface{}]bool),
+	}
+	defer c.listenerMgr.CloseAllClients()
+
+	addClient := func(ip string) interface{} {
+		conn := new(int)
+		c.listenerMgr.AddClient(&interfaces.Client{
+			Conn:    conn,
+			Address: &net.TCPAddr{IP: net.ParseIP(ip), Port: 40000},
+		})
+		return conn
+	}
+
+	old := addClient("10.0.0.1")
+	existing := map[interface{}]bool{old: true}
+	target := map[string]bool{"10.0.0.1": true}
+
+	if _, _, ok := c.claimSession(existing, target); ok {
+		t.Fatal("Expected a session open before the task not to be claimed")
+	}
+
+	addClient("10.0.0.2")
+	if _, _, ok := c.claimSession(existing, target); ok {
+		t.Fatal("Expected a session from another target not to be claimed")
+	}
+
+	addClient("10.0.0.1")
+	index, address, ok := c.claimSession(existing, target)
+	if !ok || index != 2 || address != "10.0.0.1:40000" {
+		t.Fatalf("Expected the new session of the target, got index=%d address=%q ok=%v", index, address, ok)
+	}
+
+	if _, _, ok := c.claimSession(existing, target); ok {
+		t.Fatal("Expected a session to be claimed only once")
+	}
+
+	other := map[string]bool{"10.0.0.2": true}
+	if index, _, ok := c.claimSession(existing, other); !ok || index != 1 {
+		t.Fatalf("Expected the session of the other target, got index=%d ok=%v", index, ok)
+	}
+}
+
+func TestTargetAddresses(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"http://127.0.0.1:8080/path", "127.0.0.1"},
+		{"127.0.0.1:22", "127.0.0.1"},
+		{"https://[::1]/", "::1"},
+		{"10.1.2.3", "10.1.2.3"},
+	}
+
+	for _, tt := range tests {
+		addresses, err := targetAddresses(context.Background(), tt.target)
+		if err != nil {
+			t.Errorf("targetAddresses(%q) failed: %v", tt.target, err)
+			continue
+		}
+		if len(addresses) != 1 || !addresses[tt.want] {
+			t.Errorf("targetAddresses(%q) = %v, want %s", tt.target, addresses, tt.want)
+		}
+	}
+}
+
+func TestOptionString(t *testing.T) {
+	options := map[string]interface{}{"lport": 4444, "lhost": "10.0.0.1", "empty": nil}
+
+	if got := optionString(options, "lport"); got != "4444" {
+		t.Errorf("Expected an int option as text, got %q", got)
+	}
+	if got := optionString(options, "lhost"); got != "10.0.0.1" {
+		t.Errorf("Expected a string option as is, got %q", got)
+	}
+	if got := optionString(options, "empty"); got != "" {
+		t.Errorf("Expected a nil option to be empty, got %q", got)
+	}
+	if got := optionString(options, "missing"); got != "" {
+		t.Errorf("Expected a missing option to be empty, got %q", got)
+	}
+}
+
+func TestExecuteShellRequiresLHost(t *testing.T) {
+	registerFakePOC(t, &fakePOC{name: "shell-no-lhost", run: func(context.Context, string) (*api.Output, error) {
+		t.Error("Expected the POC not to run without lhost")
+		return succeed(context.Background(), "")
+	}})
+
+	controller, _ := newTestController(t)
+	_, err := controller.ExecutePOCContext(context.Background(), "shell-no-lhost", "http://127.0.0.1", "shell")
+	if err == nil || !strings.Contains(err.Error(), "--lhost") {
+		t.Errorf("Expected an error asking for --lhost, got %v", err)
+	}
+}
+
+func TestStartConnectBackListener(t *testing.T) {
+	c := &Controller{
+		listenerMgr: listener.New(nil),
+		options:     make(map[string]interface{}),
+	}
+
+	if _, err := c.listenerMgr.GetListener("reverse_tcp"); err == nil {
+		t.Skip("reverse_tcp is already registered with the shared listener manager")
+	}
+	if err := c.startConnectBackListener("4444"); err == nil {
+		t.Fatal("Expected an error without a reverse_tcp listener")
+	}
+
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	_, lport, _ := net.SplitHostPort(free.Addr().String())
+	free.Close()
+
+	reverseTCP := listener.NewReverseTCP(nil)
+	c.listenerMgr.RegisterListener("reverse_tcp", reverseTCP)
+	if err := c.startConnectBackListener(lport); err != nil {
+		t.Fatalf("Failed to start the listener: %v", err)
+	}
+	defer reverseTCP.Stop()
+
+	if err := c.startConnectBackListener(lport); err != nil {
+		t.Errorf("Expected the running listener to be reused, got %v", err)
+	}
+	if err := c.startConnectBackListener("1"); err == nil || !strings.Contains(err.Error(), "port "+lport) {
+		t.Errorf("Expected an error naming the running port, got %v", err)
+	}
+}
@@ -46,11 +46,11 @@ func (sm *SessionManager) GetSession(sessionID string) *http.Client {
 	jar, _ := cookiejar.New(nil)
 	client = &http.Client{
 		Jar: jar,
-		Transport: &http.Transport{
+		Transport: request.LimitTransport(&http.Transport{
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: true,
 			},
-		},
+		}, nil),
 		Timeout: 30 * time.Second,
 	}
 
@@ -58,6 +58,12 @@ func (sm *SessionManager) GetSession(sessionID string) *http.Client {
 	return client
 }
 
+// GetCookieJar returns the cookie jar of a session, so that clients other
+// than the session's own can share its cookies.
+func (sm *SessionManager) GetCookieJar(sessionID string) http.CookieJar {
+	return sm.GetSession(sessionID).Jar
+}
+
 func (sm *SessionManager) RemoveSession(sessionID string) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -212,6 +218,11 @@ func (rm *RequestManager) RemoveHook(hook RequestHook) {
 }
 
 func (rm *RequestManager) Execute(config *RequestConfig) (*request.Response, error) {
+	return rm.ExecuteContext(context.Background(), config)
+}
+
+// ExecuteContext is Execute, giving up as soon as ctx is done.
+func (rm *RequestManager) ExecuteContext(ctx context.Context, config *RequestConfig) (*request.Response, error) {
 	if config == nil {
 		config = rm.defaultConfig
 	}
@@ -240,7 +251,7 @@ func (rm *RequestManager) Execute(config *RequestConfig) (*request.Response, err
 		}
 
 		client = &http.Client{
-			Transport: transport,
+			Transport: request.LimitTransport(transport, nil),
 			Timeout:   config.Timeout,
 		}
 		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
@@ -267,7 +278,7 @@ func (rm *RequestManager) Execute(config *RequestConfig) (*request.Response, err
 		}
 	}
 
-	req, err := http.NewRequest(config.Method, config.URL, body)
+	req, err := http.NewRequestWithContext(ctx, config.Method, config.URL, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -447,7 +458,7 @@ func RequestWithContext(ctx context.Context, config *RequestConfig) (*request.Re
 	}
 
 	client := &http.Client{
-		Transport: transport,
+		Transport: request.LimitTransport(transport, nil),
 		Timeout:   config.Timeout,
 	}
 	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
@@ -0,0 +1,81 @@
+package fingerprint
+
+import "strconv"
+
+// categoryNames maps Wappalyzer category IDs to their names. A signature
+// database may add to it or override it with a "categories" key.
+var categoryNames = map[int]string{
+	1:  "CMS",
+	2:  "Message boards",
+	3:  "Database managers",
+	4:  "Documentation",
+	5:  "Widgets",
+	6:  "Ecommerce",
+	7:  "Photo galleries",
+	8:  "Wikis",
+	9:  "Hosting panels",
+	10: "Analytics",
+	11: "Blogs",
+	12: "JavaScript frameworks",
+	13: "Issue trackers",
+	14: "Video players",
+	15: "Comment systems",
+	16: "Security",
+	17: "Font scripts",
+	18: "Web frameworks",
+	19: "Miscellaneous",
+	20: "Editors",
+	21: "LMS",
+	22: "Web servers",
+	23: "Caching",
+	24: "Rich text editors",
+	25: "JavaScript graphics",
+	26: "Mobile frameworks",
+	27: "Programming languages",
+	28: "Operating systems",
+	29: "Search engines",
+	30: "Webmail",
+	31: "CDN",
+	32: "Marketing automation",
+	33: "Web server extensions",
+	34: "Databases",
+	35: "Maps",
+	36: "Advertising",
+	37: "Network devices",
+	38: "Media servers",
+	39: "Webcams",
+	41: "Payment processors",
+	42: "Tag managers",
+	44: "CI",
+	45: "Control systems",
+	46: "Remote access",
+	47: "Development",
+	48: "Network storage",
+	49: "Feed readers",
+	50: "Document management",
+	51: "Page builders",
+	52: "Live chat",
+	53: "CRM",
+	59: "JavaScript libraries",
+	60: "Containers",
+	62: "PaaS",
+	63: "IaaS",
+	64: "Reverse proxies",
+	65: "Load balancers",
+	66: "UI frameworks",
+}
+
+// category is an entry of the "categories" key of a signature database,
+// keyed by ID as in Wappalyzer's categories.json.
+type category struct {
+	Name string `json:"name"`
+}
+
+// categoryName returns the name of category id in names, or the ID itself
+// when it is unknown.
+func categoryName(names map[int]string, id int) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return strconv.Itoa(id)
+}
@@ -0,0 +1,72 @@
+package fingerprint
+
+import (
+	"encoding/base64"
+	"math/bits"
+	"strings"
+)
+
+// faviconHash returns the hash Shodan and FOFA index favicons by: the 32
+// bit MurmurHash3 of the icon's base64 encoding, wrapped at 76 characters
+// with a trailing newline.
+func faviconHash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	var wrapped strings.Builder
+	for len(encoded) > 76 {
+		wrapped.WriteString(encoded[:76])
+		wrapped.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	wrapped.WriteString(encoded)
+	wrapped.WriteByte('\n')
+
+	return int32(murmur3([]byte(wrapped.String()), 0))
+}
+
+// murmur3 is MurmurHash3 x86_32.
+func murmur3(data []byte, seed uint32) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	h := seed
+	n := len(data) / 4 * 4
+
+	for i := 0; i < n; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	switch len(data) & 3 {
+	case 3:
+		k ^= uint32(data[n+2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(data[n+1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(data[n])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+
+	return h
+}
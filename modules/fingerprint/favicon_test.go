@@ -0,0 +1,63 @@
+package fingerprint
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMurmur3(t *testing.T) {
+	tests := []struct {
+		data string
+		seed uint32
+		want uint32
+	}{
+		{"", 0, 0},
+		{"", 1, 0x514e28b7},
+		{"", 0xffffffff, 0x81f16f39},
+		{"\xff\xff\xff\xff", 0, 0x76293b50},
+		{"\x21\x43\x65\x87", 0, 0xf55b516b},
+		{"\x21\x43\x65\x87", 0x5082edee, 0x2362f9de},
+		{"\x21\x43\x65", 0, 0x7e4a8634},
+		{"\x21\x43", 0, 0xa0f7b07a},
+		{"\x21", 0, 0x72661cf4},
+		{"\x00\x00\x00\x00", 0, 0x2362f9de},
+		{"foo", 0, 4138058784},
+		{"Hello, world!", 0x9747b28c, 0x24884cba},
+		{"The quick brown fox jumps over the lazy dog", 0x9747b28c, 0x2fa826cd},
+	}
+
+	for _, tt := range tests {
+		if got := murmur3([]byte(tt.data), tt.seed); got != tt.want {
+			t.Errorf("murmur3(%q, %#x) = %#x, want %#x", tt.data, tt.seed, got, tt.want)
+		}
+	}
+}
+
+// The expected hashes are those of mmh3.hash(base64.encodebytes(data)),
+// which is how Shodan computes http.favicon.hash.
+func TestFaviconHash(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int32
+	}{
+		{"short", []byte("GIF89a"), -851503336},
+		{"one full line", bytes.Repeat([]byte{0}, 57), 1993561383},
+		{"one line over", bytes.Repeat([]byte{0}, 58), -1275236913},
+		{"several lines", bytes.Repeat(byteRange(), 2), -1173581353},
+	}
+
+	for _, tt := range tests {
+		if got := faviconHash(tt.data); got != tt.want {
+			t.Errorf("faviconHash(%s) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func byteRange() []byte {
+	data := make([]byte, 256)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return data
+}
@@ -0,0 +1,220 @@
+package fingerprint
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seaung/pocsuite-go/config"
+	"github.com/seaung/pocsuite-go/modules/interfaces"
+	"github.com/seaung/pocsuite-go/request"
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	maxBodySize    = 2 << 20
+)
+
+//go:embed signatures.json
+var defaultSignatures []byte
+
+// Fingerprint detects the technologies a web target runs by matching its
+// response headers, cookies, meta tags, script paths, HTML and favicon hash
+// against a Wappalyzer style signature database. The database is read from
+// the "signatures" key of the Fingerprint config section, and falls back to
+// the built-in one.
+type Fingerprint struct {
+	client       *http.Client
+	config       *config.Config
+	technologies map[string]*technology
+}
+
+func New(config *config.Config) *Fingerprint {
+	return &Fingerprint{
+		client: &http.Client{
+			Transport: request.LimitTransport(nil, nil),
+			Timeout:   defaultTimeout,
+		},
+		config: config,
+	}
+}
+
+func (f *Fingerprint) Name() string {
+	return "fingerprint"
+}
+
+func (f *Fingerprint) Init() error {
+	if timeout, ok := f.config.Get("Fingerprint", "timeout"); ok {
+		if seconds, err := strconv.Atoi(timeout); err == nil && seconds > 0 {
+			f.client.Timeout = time.Duration(seconds) * time.Second
+		}
+	}
+
+	data := defaultSignatures
+	if path, ok := f.config.Get("Fingerprint", "signatures"); ok && path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read signatures: %w", err)
+		}
+	}
+
+	technologies, err := parseSignatures(data)
+	if err != nil {
+		return err
+	}
+	f.technologies = technologies
+
+	return nil
+}
+
+func (f *Fingerprint) IsAvailable() bool {
+	return f.technologies != nil
+}
+
+// Fingerprint returns the technologies detected on target, sorted by name.
+func (f *Fingerprint) Fingerprint(ctx context.Context, target string) ([]interfaces.Technology, error) {
+	if !f.IsAvailable() {
+		return nil, fmt.Errorf("fingerprint module is not initialized")
+	}
+
+	p, err := f.fetch(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	var technologies []interfaces.Technology
+	for _, d := range analyze(f.technologies, p) {
+		technologies = append(technologies, interfaces.Technology{
+			Name:       d.tech.name,
+			Version:    d.version,
+			CPE:        d.cpe(),
+			Categories: d.tech.categories,
+		})
+	}
+
+	return technologies, nil
+}
+
+// fetch requests target and its favicon, and collects what signatures are
+// matched against.
+func (f *Fingerprint) fetch(ctx context.Context, target string) (*page, error) {
+	resp, err := f.get(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch target: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	p := &page{
+		headers: make(map[string][]string, len(resp.Header)),
+		cookies: make(map[string]string),
+		meta:    make(map[string]string),
+		html:    string(body),
+	}
+
+	for name, values := range resp.Header {
+		p.headers[strings.ToLower(name)] = values
+	}
+	for _, cookie := range resp.Cookies() {
+		p.cookies[strings.ToLower(cookie.Name)] = cookie.Value
+	}
+
+	iconURL := "/favicon.ico"
+	if doc, err := html.Parse(strings.NewReader(p.html)); err == nil {
+		iconURL = parseDocument(doc, p, iconURL)
+	}
+
+	base := resp.Request.URL
+	if ref, err := url.Parse(iconURL); err == nil {
+		if hash, ok := f.fetchFavicon(ctx, base.ResolveReference(ref).String()); ok {
+			p.favicon = &hash
+		}
+	}
+
+	return p, nil
+}
+
+// parseDocument collects the meta tags and script paths of doc, and
+// returns the favicon it links to, or iconURL when it links none.
+func parseDocument(doc *html.Node, p *page, iconURL string) string {
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "meta":
+				name := attr(n, "name")
+				if name == "" {
+					name = attr(n, "property")
+				}
+				if name != "" {
+					p.meta[strings.ToLower(name)] = attr(n, "content")
+				}
+			case "script":
+				if src := attr(n, "src"); src != "" {
+					p.scriptSrc = append(p.scriptSrc, src)
+				}
+			case "link":
+				for _, rel := range strings.Fields(strings.ToLower(attr(n, "rel"))) {
+					if rel == "icon" && attr(n, "href") != "" {
+						iconURL = attr(n, "href")
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(doc)
+
+	return iconURL
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func (f *Fingerprint) fetchFavicon(ctx context.Context, iconURL string) (int32, bool) {
+	resp, err := f.get(ctx, iconURL)
+	if err != nil {
+		return 0, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, false
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil || len(data) == 0 {
+		return 0, false
+	}
+
+	return faviconHash(data), true
+}
+
+func (f *Fingerprint) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.client.Do(req)
+}
@@ -0,0 +1,112 @@
+package fingerprint
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/seaung/pocsuite-go/config"
+)
+
+func newTestFingerprint(t *testing.T) *Fingerprint {
+	t.Helper()
+
+	cfg, err := config.NewConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+
+	f := New(cfg)
+	if err := f.Init(); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	return f
+}
+
+func TestFingerprint(t *testing.T) {
+	icon := []byte("GIF89a")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/static/icon.gif":
+			w.Write(icon)
+		case "/":
+			w.Header().Set("Server", "nginx/1.18.0")
+			http.SetCookie(w, &http.Cookie{Name: "PHPSESSID", Value: "abc"})
+			w.Write([]byte(`<html><head>
+<meta name="generator" content="WordPress 6.4.2">
+<link rel="icon" href="/static/icon.gif">
+</head></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := newTestFingerprint(t)
+	f.technologies["Custom"] = &technology{name: "Custom", favicon: []int32{faviconHash(icon)}}
+
+	technologies, err := f.Fingerprint(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Failed to fingerprint: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, tech := range technologies {
+		got[tech.Name] = tech.Version
+	}
+	want := map[string]string{"Custom": "", "Nginx": "1.18.0", "PHP": "", "WordPress": "6.4.2"}
+	if len(got) != len(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	for name, version := range want {
+		if v, ok := got[name]; !ok || v != version {
+			t.Errorf("Expected %s %q, got %v", name, version, got)
+		}
+	}
+
+	for _, tech := range technologies {
+		if tech.Name == "Nginx" {
+			if tech.CPE != "cpe:2.3:a:f5:nginx:1.18.0:*:*:*:*:*:*:*" {
+				t.Errorf("Expected the versioned CPE, got %s", tech.CPE)
+			}
+			if len(tech.Categories) != 2 || tech.Categories[0] != "Web servers" || tech.Categories[1] != "Reverse proxies" {
+				t.Errorf("Expected the category names, got %v", tech.Categories)
+			}
+		}
+	}
+}
+
+func TestFingerprintContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	f := newTestFingerprint(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := f.Fingerprint(ctx, server.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected fingerprinting to stop at the deadline, took %v", elapsed)
+	}
+}
+
+func TestFingerprintNotInitialized(t *testing.T) {
+	if _, err := New(nil).Fingerprint(context.Background(), "http://127.0.0.1"); err == nil {
+		t.Error("Expected an error before Init")
+	}
+}
@@ -0,0 +1,303 @@
+package fingerprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// signature is one technology of a Wappalyzer style database. Patterns are
+// regular expressions, matched case-insensitively, optionally followed by
+// "\;version:\1" to take the version from a capture group. Cats are
+// category IDs.
+type signature struct {
+	Cats      []int             `json:"cats"`
+	CPE       string            `json:"cpe"`
+	Headers   map[string]string `json:"headers"`
+	Cookies   map[string]string `json:"cookies"`
+	Meta      map[string]string `json:"meta"`
+	ScriptSrc stringList        `json:"scriptSrc"`
+	HTML      stringList        `json:"html"`
+	Favicon   []int32           `json:"favicon"`
+	Implies   stringList        `json:"implies"`
+}
+
+// stringList accepts either a single string or a list of strings, as
+// Wappalyzer does for most pattern fields.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*l = stringList{value}
+		return nil
+	}
+
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+type pattern struct {
+	re      *regexp.Regexp
+	version string
+}
+
+type technology struct {
+	name       string
+	categories []string
+	cpe        string
+	headers    map[string]*pattern
+	cookies    map[string]*pattern
+	meta       map[string]*pattern
+	scriptSrc  []*pattern
+	html       []*pattern
+	favicon    []int32
+	implies    []string
+}
+
+// parseSignatures decodes a signature database, either a plain object of
+// technologies or one wrapped in a "technologies" key, next to which a
+// "categories" key may name the categories.
+func parseSignatures(data []byte) (map[string]*technology, error) {
+	var wrapped struct {
+		Categories   map[int]category     `json:"categories"`
+		Technologies map[string]signature `json:"technologies"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, fmt.Errorf("failed to parse signatures: %w", err)
+	}
+
+	categories := make(map[int]string, len(categoryNames)+len(wrapped.Categories))
+	for id, name := range categoryNames {
+		categories[id] = name
+	}
+	for id, c := range wrapped.Categories {
+		categories[id] = c.Name
+	}
+
+	signatures := wrapped.Technologies
+	if signatures == nil {
+		if err := json.Unmarshal(data, &signatures); err != nil {
+			return nil, fmt.Errorf("failed to parse signatures: %w", err)
+		}
+	}
+
+	technologies := make(map[string]*technology, len(signatures))
+	for name, sig := range signatures {
+		tech, err := compileSignature(name, sig, categories)
+		if err != nil {
+			return nil, err
+		}
+		technologies[name] = tech
+	}
+
+	return technologies, nil
+}
+
+func compileSignature(name string, sig signature, categories map[int]string) (*technology, error) {
+	tech := &technology{
+		name:    name,
+		cpe:     sig.CPE,
+		favicon: sig.Favicon,
+	}
+	for _, id := range sig.Cats {
+		tech.categories = append(tech.categories, categoryName(categories, id))
+	}
+
+	var err error
+	compileMap := func(patterns map[string]string) map[string]*pattern {
+		compiled := make(map[string]*pattern, len(patterns))
+		for key, p := range patterns {
+			if err != nil {
+				break
+			}
+			compiled[strings.ToLower(key)], err = compilePattern(p)
+		}
+		return compiled
+	}
+	compileList := func(patterns []string) []*pattern {
+		compiled := make([]*pattern, 0, len(patterns))
+		for _, p := range patterns {
+			if err != nil {
+				break
+			}
+			var c *pattern
+			c, err = compilePattern(p)
+			compiled = append(compiled, c)
+		}
+		return compiled
+	}
+
+	tech.headers = compileMap(sig.Headers)
+	tech.cookies = compileMap(sig.Cookies)
+	tech.meta = compileMap(sig.Meta)
+	tech.scriptSrc = compileList(sig.ScriptSrc)
+	tech.html = compileList(sig.HTML)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature for %s: %w", name, err)
+	}
+
+	for _, implied := range sig.Implies {
+		implied, _, _ = strings.Cut(implied, `\;`)
+		tech.implies = append(tech.implies, implied)
+	}
+
+	return tech, nil
+}
+
+func compilePattern(p string) (*pattern, error) {
+	fields := strings.Split(p, `\;`)
+
+	re, err := regexp.Compile("(?i)" + fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := &pattern{re: re}
+	for _, field := range fields[1:] {
+		if version, ok := strings.CutPrefix(field, "version:"); ok {
+			compiled.version = version
+		}
+	}
+	return compiled, nil
+}
+
+// match reports whether s matches, along with the version the pattern
+// extracts from it, if any.
+func (p *pattern) match(s string) (bool, string) {
+	groups := p.re.FindStringSubmatch(s)
+	if groups == nil {
+		return false, ""
+	}
+	if p.version == "" {
+		return true, ""
+	}
+
+	version := p.version
+	for i := len(groups) - 1; i > 0; i-- {
+		version = strings.ReplaceAll(version, `\`+strconv.Itoa(i), groups[i])
+	}
+	return true, strings.TrimSpace(version)
+}
+
+// page is what a target gave away about itself.
+type page struct {
+	headers   map[string][]string
+	cookies   map[string]string
+	meta      map[string]string
+	scriptSrc []string
+	html      string
+	favicon   *int32
+}
+
+type detection struct {
+	matched bool
+	version string
+}
+
+func (d *detection) add(matched bool, version string) {
+	if !matched {
+		return
+	}
+	d.matched = true
+	if d.version == "" {
+		d.version = version
+	}
+}
+
+func (t *technology) detect(p *page) detection {
+	var d detection
+
+	for name, pat := range t.headers {
+		for _, value := range p.headers[name] {
+			d.add(pat.match(value))
+		}
+	}
+	for name, pat := range t.cookies {
+		if value, ok := p.cookies[name]; ok {
+			d.add(pat.match(value))
+		}
+	}
+	for name, pat := range t.meta {
+		if content, ok := p.meta[name]; ok {
+			d.add(pat.match(content))
+		}
+	}
+	for _, pat := range t.scriptSrc {
+		for _, src := range p.scriptSrc {
+			d.add(pat.match(src))
+		}
+	}
+	for _, pat := range t.html {
+		d.add(pat.match(p.html))
+	}
+	if p.favicon != nil {
+		for _, hash := range t.favicon {
+			d.add(hash == *p.favicon, "")
+		}
+	}
+
+	return d
+}
+
+// analyze returns the technologies of db detected in p, along with the
+// ones they imply, sorted by name.
+func analyze(db map[string]*technology, p *page) []detected {
+	found := make(map[string]*detected)
+
+	for name, tech := range db {
+		if d := tech.detect(p); d.matched {
+			found[name] = &detected{tech: tech, version: d.version}
+		}
+	}
+
+	var imply func(names []string)
+	imply = func(names []string) {
+		for _, name := range names {
+			tech, ok := db[name]
+			if !ok || found[name] != nil {
+				continue
+			}
+			found[name] = &detected{tech: tech}
+			imply(tech.implies)
+		}
+	}
+	for _, d := range found {
+		imply(d.tech.implies)
+	}
+
+	results := make([]detected, 0, len(found))
+	for _, d := range found {
+		results = append(results, *d)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].tech.name < results[j].tech.name
+	})
+
+	return results
+}
+
+type detected struct {
+	tech    *technology
+	version string
+}
+
+// cpe fills the version into the technology's CPE 2.3 name.
+func (d detected) cpe() string {
+	if d.tech.cpe == "" || d.version == "" {
+		return d.tech.cpe
+	}
+
+	fields := strings.Split(d.tech.cpe, ":")
+	if len(fields) > 5 {
+		fields[5] = d.version
+	}
+	return strings.Join(fields, ":")
+}
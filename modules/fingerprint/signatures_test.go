@@ -0,0 +1,195 @@
+package fingerprint
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestParseSignatures(t *testing.T) {
+	technologies, err := parseSignatures(defaultSignatures)
+	if err != nil {
+		t.Fatalf("Failed to parse the built-in signatures: %v", err)
+	}
+
+	gitlab, ok := technologies["GitLab"]
+	if !ok {
+		t.Fatal("Expected GitLab in the built-in signatures")
+	}
+	if want := []string{"Issue trackers", "Development"}; !reflect.DeepEqual(gitlab.categories, want) {
+		t.Errorf("Expected categories %v, got %v", want, gitlab.categories)
+	}
+	if want := []string{"Ruby on Rails"}; !reflect.DeepEqual(gitlab.implies, want) {
+		t.Errorf("Expected implies %v, got %v", want, gitlab.implies)
+	}
+
+	technologies, err = parseSignatures([]byte(`{
+		"categories": {"22": {"name": "Servers"}, "1000": {"name": "Custom"}},
+		"technologies": {"Example": {"cats": [22, 1000, 2000, 1], "implies": "Other\\;confidence:50"}}
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to parse signatures: %v", err)
+	}
+	example := technologies["Example"]
+	if want := []string{"Servers", "Custom", "2000", "CMS"}; !reflect.DeepEqual(example.categories, want) {
+		t.Errorf("Expected categories %v, got %v", want, example.categories)
+	}
+	if want := []string{"Other"}; !reflect.DeepEqual(example.implies, want) {
+		t.Errorf("Expected implies %v, got %v", want, example.implies)
+	}
+
+	technologies, err = parseSignatures([]byte(`{"Plain": {"cats": [27], "html": "plain"}}`))
+	if err != nil {
+		t.Fatalf("Failed to parse a plain signature object: %v", err)
+	}
+	if plain, ok := technologies["Plain"]; !ok || len(plain.html) != 1 {
+		t.Errorf("Expected the plain object to be read, got %v", technologies)
+	}
+
+	if _, err := parseSignatures([]byte(`{"Broken": {"html": "("}}`)); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+	if _, err := parseSignatures([]byte(`{"Broken": {"cats": ["CMS"]}}`)); err == nil {
+		t.Error("Expected category names in cats to be rejected")
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		matched bool
+		version string
+	}{
+		{`^nginx(?:/([\d.]+))?\;version:\1`, "nginx/1.18.0", true, "1.18.0"},
+		{`^nginx(?:/([\d.]+))?\;version:\1`, "NGINX", true, ""},
+		{`^nginx(?:/([\d.]+))?\;version:\1`, "Apache", false, ""},
+		{`([\d]+)\.([\d]+)\;version:\2.\1`, "v1.2", true, "2.1"},
+		{`jquery[.-]([\d.]*\d)[^/]*\.js\;version:\1\;confidence:50`, "/js/jquery-3.6.0.min.js", true, "3.6.0"},
+		{``, "anything", true, ""},
+	}
+
+	for _, tt := range tests {
+		p, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("Failed to compile %q: %v", tt.pattern, err)
+		}
+
+		matched, version := p.match(tt.value)
+		if matched != tt.matched || version != tt.version {
+			t.Errorf("%q on %q = %v %q, want %v %q", tt.pattern, tt.value, matched, version, tt.matched, tt.version)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	db, err := parseSignatures(defaultSignatures)
+	if err != nil {
+		t.Fatalf("Failed to parse the built-in signatures: %v", err)
+	}
+
+	favicon := int32(116323821)
+
+	tests := []struct {
+		name string
+		page *page
+		want map[string]string
+	}{
+		{
+			name: "header version",
+			page: &page{headers: map[string][]string{"server": {"nginx/1.18.0"}}},
+			want: map[string]string{"Nginx": "1.18.0"},
+		},
+		{
+			name: "header and cookie with implies",
+			page: &page{
+				headers: map[string][]string{"x-jenkins": {"2.401.3"}},
+				cookies: map[string]string{"jsessionid": "abc"},
+			},
+			want: map[string]string{"Jenkins": "2.401.3", "Java": ""},
+		},
+		{
+			name: "meta version",
+			page: &page{meta: map[string]string{"generator": "WordPress 6.4.2"}},
+			want: map[string]string{"WordPress": "6.4.2", "PHP": ""},
+		},
+		{
+			name: "html version",
+			page: &page{html: "<html><title>Apache Tomcat/9.0.65</title></html>"},
+			want: map[string]string{"Apache Tomcat": "9.0.65", "Java": ""},
+		},
+		{
+			name: "script source",
+			page: &page{scriptSrc: []string{"/static/jquery-3.6.0.min.js"}},
+			want: map[string]string{"jQuery": "3.6.0"},
+		},
+		{
+			name: "favicon",
+			page: &page{favicon: &favicon},
+			want: map[string]string{"Spring Boot": "", "Java": ""},
+		},
+		{
+			name: "nothing",
+			page: &page{html: "<html></html>"},
+			want: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]string)
+			var names []string
+			for _, d := range analyze(db, tt.page) {
+				got[d.tech.name] = d.version
+				names = append(names, d.tech.name)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+			for i := 1; i < len(names); i++ {
+				if names[i-1] > names[i] {
+					t.Errorf("Expected detections sorted by name, got %v", names)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectedCPE(t *testing.T) {
+	tech := &technology{cpe: "cpe:2.3:a:f5:nginx:*:*:*:*:*:*:*:*"}
+
+	if got := (detected{tech: tech, version: "1.18.0"}).cpe(); got != "cpe:2.3:a:f5:nginx:1.18.0:*:*:*:*:*:*:*" {
+		t.Errorf("Expected the version in the CPE, got %s", got)
+	}
+	if got := (detected{tech: tech}).cpe(); got != tech.cpe {
+		t.Errorf("Expected the CPE unchanged without a version, got %s", got)
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><head>
+<meta name="Generator" content="Joomla! 4.2">
+<meta property="og:site_name" content="GitLab">
+<link rel="shortcut icon" href="/static/icon.png">
+<script src="/js/app.js"></script>
+</head></html>`))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	p := &page{meta: make(map[string]string)}
+	iconURL := parseDocument(doc, p, "/favicon.ico")
+
+	if iconURL != "/static/icon.png" {
+		t.Errorf("Expected the linked icon, got %s", iconURL)
+	}
+	if p.meta["generator"] != "Joomla! 4.2" || p.meta["og:site_name"] != "GitLab" {
+		t.Errorf("Expected the meta tags by lower-case name, got %v", p.meta)
+	}
+	if !reflect.DeepEqual(p.scriptSrc, []string{"/js/app.js"}) {
+		t.Errorf("Expected the script sources, got %v", p.scriptSrc)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/seaung/pocsuite-go/config"
 	"github.com/seaung/pocsuite-go/modules/censys"
 	"github.com/seaung/pocsuite-go/modules/ceye"
+	"github.com/seaung/pocsuite-go/modules/fingerprint"
 	"github.com/seaung/pocsuite-go/modules/fofa"
 	"github.com/seaung/pocsuite-go/modules/httpserver"
 	"github.com/seaung/pocsuite-go/modules/hunter"
@@ -69,6 +70,10 @@ func InitModules() error {
 		return fmt.Errorf("failed to register shellcodes modules: %w", err)
 	}
 
+	if err := registerFingerprintModules(); err != nil {
+		return fmt.Errorf("failed to register fingerprint modules: %w", err)
+	}
+
 	if err := registerPlugins(); err != nil {
 		return fmt.Errorf("failed to register plugins: %w", err)
 	}
@@ -181,6 +186,20 @@ func registerShellcodesModules() error {
 	return nil
 }
 
+func registerFingerprintModules() error {
+	fingerprintModules := []Fingerprinter{
+		fingerprint.New(GlobalConfig),
+	}
+
+	for _, module := range fingerprintModules {
+		if err := manager.GlobalManager.Register(module); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func GetAvailableModules() []string {
 	return manager.GlobalManager.List()
 }
@@ -202,6 +221,8 @@ func GetModuleInfo() map[string]interface{} {
 
 	info["shellcodes"] = []string{"shellcodes"}
 
+	info["fingerprinters"] = []string{"fingerprint"}
+
 	info["plugins"] = []string{
 		"file_record", "html_report", "console_output",
 		"poc_from_file", "poc_from_dir", "poc_from_seebug", "poc_from_cve",
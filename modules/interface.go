@@ -13,5 +13,6 @@ type HTTPServer = interfaces.HTTPServer
 type Listener = interfaces.ListenerModule
 type Spider = interfaces.Spider
 type Shellcodes = interfaces.Shellcodes
+type Fingerprinter = interfaces.Fingerprinter
 
 type ModuleManager = manager.ModuleManager
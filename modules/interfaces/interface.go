@@ -1,5 +1,7 @@
 package interfaces
 
+import "context"
+
 type Module interface {
 	Name() string
 	Init() error
@@ -48,6 +50,19 @@ type Spider interface {
 	Crawl(url string, depth int) ([]string, error)
 }
 
+type Fingerprinter interface {
+	Module
+	Fingerprint(ctx context.Context, target string) ([]Technology, error)
+}
+
+// Technology is a product detected on a target.
+type Technology struct {
+	Name       string
+	Version    string
+	CPE        string
+	Categories []string
+}
+
 type Shellcodes interface {
 	Module
 	CreateOSShellcode(osTarget string, arch string, shellcodeType string, connectbackIP string, connectbackPort int, encoding string) ([]byte, error)
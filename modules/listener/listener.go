@@ -102,6 +102,10 @@ func (lm *ListenerManager) GetClient(index int) (*interfaces.Client, error) {
 }
 
 func (lm *ListenerManager) RegisterListener(name string, listener ListenerModule) {
+	if managed, ok := listener.(interface{ SetManager(*ListenerManager) }); ok {
+		managed.SetManager(lm)
+	}
+
 	lm.listenersMu.Lock()
 	defer lm.listenersMu.Unlock()
 	lm.listeners[name] = listener
@@ -86,6 +86,15 @@ func (m *ModuleManager) GetSpider(name string) (interfaces.Spider, bool) {
 	return spider, ok
 }
 
+func (m *ModuleManager) GetFingerprinter(name string) (interfaces.Fingerprinter, bool) {
+	module, exists := m.modules[name]
+	if !exists {
+		return nil, false
+	}
+	fingerprinter, ok := module.(interfaces.Fingerprinter)
+	return fingerprinter, ok
+}
+
 func (m *ModuleManager) GetShellcodes(name string) (interfaces.Shellcodes, bool) {
 	module, exists := m.modules[name]
 	if !exists {
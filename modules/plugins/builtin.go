@@ -1,17 +1,23 @@
 package plugins
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
+	"io"
 	"os"
 	"sync"
 	"time"
+
+	"github.com/seaung/pocsuite-go/api"
 )
 
 type FileRecordPlugin struct {
 	*PluginBase
 	filename string
 	file     *os.File
+	count    int
 	mu       sync.Mutex
 }
 
@@ -30,11 +36,41 @@ func (p *FileRecordPlugin) Init() error {
 		p.filename = fmt.Sprintf("pocsuite_results_%s.json", time.Now().Format("20060102_150405"))
 	}
 
+	return p.open(false)
+}
+
+// Open records the results to filename from now on. With appendMode, they
+// are added to the results the file already holds, even when the run that
+// wrote them did not finish; otherwise the file is truncated.
+func (p *FileRecordPlugin) Open(filename string, appendMode bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.close(); err != nil {
+		return err
+	}
+
+	p.filename = filename
+	return p.open(appendMode)
+}
+
+func (p *FileRecordPlugin) open(appendMode bool) error {
+	if appendMode {
+		data, err := os.ReadFile(p.filename)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			return p.reopen(data)
+		}
+	}
+
 	file, err := os.OpenFile(p.filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	p.file = file
+	p.count = 0
 
 	if _, err := p.file.WriteString("[\n"); err != nil {
 		return fmt.Errorf("failed to write to file: %w", err)
@@ -43,6 +79,38 @@ func (p *FileRecordPlugin) Init() error {
 	return nil
 }
 
+// reopen continues the JSON array held in data: its closing bracket, if it
+// was written, is cut off so that results can be added after the last one.
+func (p *FileRecordPlugin) reopen(data []byte) error {
+	content := bytes.TrimRight(data, " \t\r\n")
+	if !bytes.HasPrefix(bytes.TrimSpace(content), []byte("[")) {
+		return fmt.Errorf("%s does not hold recorded results", p.filename)
+	}
+	content = bytes.TrimSuffix(content, []byte("]"))
+	content = bytes.TrimRight(content, " \t\r\n,")
+
+	file, err := os.OpenFile(p.filename, os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	if err := file.Truncate(int64(len(content))); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to truncate file: %w", err)
+	}
+	if _, err := file.Seek(int64(len(content)), io.SeekStart); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to seek file: %w", err)
+	}
+
+	p.file = file
+	p.count = 0
+	if !bytes.HasSuffix(content, []byte("[")) {
+		p.count = 1
+	}
+
+	return nil
+}
+
 func (p *FileRecordPlugin) Start() error {
 	return nil
 }
@@ -51,6 +119,10 @@ func (p *FileRecordPlugin) Stop() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	return p.close()
+}
+
+func (p *FileRecordPlugin) close() error {
 	if p.file != nil {
 		if _, err := p.file.WriteString("\n]\n"); err != nil {
 			return fmt.Errorf("failed to write to file: %w", err)
@@ -64,6 +136,14 @@ func (p *FileRecordPlugin) Stop() error {
 }
 
 func (p *FileRecordPlugin) Handle(output interface{}) error {
+	result, err := asResult(output)
+	if err != nil {
+		return err
+	}
+	return p.AddResult(result)
+}
+
+func (p *FileRecordPlugin) AddResult(result *api.Result) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -71,23 +151,24 @@ func (p *FileRecordPlugin) Handle(output interface{}) error {
 		return fmt.Errorf("file not initialized")
 	}
 
-	data, err := json.MarshalIndent(output, "  ", "  ")
+	data, err := json.MarshalIndent(result, "  ", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal output: %w", err)
 	}
 
-	if _, err := p.file.WriteString("  " + string(data) + ",\n"); err != nil {
+	separator := ""
+	if p.count > 0 {
+		separator = ",\n"
+	}
+	if _, err := p.file.WriteString(separator + "  " + string(data)); err != nil {
 		return fmt.Errorf("failed to write to file: %w", err)
 	}
+	p.count++
 
 	return nil
 }
 
-func (p *FileRecordPlugin) AddResult(result interface{}) error {
-	return p.Handle(result)
-}
-
-func (p *FileRecordPlugin) GetResults() []interface{} {
+func (p *FileRecordPlugin) GetResults() []*api.Result {
 	return nil
 }
 
@@ -97,14 +178,14 @@ func (p *FileRecordPlugin) Export(filename string) error {
 
 type HTMLReportPlugin struct {
 	*PluginBase
-	results []interface{}
+	results []*api.Result
 	mu      sync.Mutex
 }
 
 func NewHTMLReportPlugin() *HTMLReportPlugin {
 	return &HTMLReportPlugin{
 		PluginBase: NewPluginBase(CategoryResults, "html_report"),
-		results:    make([]interface{}, 0),
+		results:    make([]*api.Result, 0),
 	}
 }
 
@@ -121,22 +202,26 @@ func (p *HTMLReportPlugin) Stop() error {
 }
 
 func (p *HTMLReportPlugin) Handle(output interface{}) error {
+	result, err := asResult(output)
+	if err != nil {
+		return err
+	}
+	return p.AddResult(result)
+}
+
+func (p *HTMLReportPlugin) AddResult(result *api.Result) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	p.results = append(p.results, output)
+	p.results = append(p.results, result)
 	return nil
 }
 
-func (p *HTMLReportPlugin) AddResult(result interface{}) error {
-	return p.Handle(result)
-}
-
-func (p *HTMLReportPlugin) GetResults() []interface{} {
+func (p *HTMLReportPlugin) GetResults() []*api.Result {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	results := make([]interface{}, len(p.results))
+	results := make([]*api.Result, len(p.results))
 	copy(results, p.results)
 	return results
 }
@@ -155,7 +240,14 @@ func (p *HTMLReportPlugin) Export(filename string) error {
 	}
 	defer file.Close()
 
-	html := `<!DOCTYPE html>
+	vulnerable := 0
+	for _, result := range p.results {
+		if result.Vulnerable() {
+			vulnerable++
+		}
+	}
+
+	header := `<!DOCTYPE html>
 <html lang="en">
 <head>
     <meta charset="UTF-8">
@@ -220,12 +312,13 @@ func (p *HTMLReportPlugin) Export(filename string) error {
         <div class="summary">
             <h2>Summary</h2>
             <p>Total Results: ` + fmt.Sprintf("%d", len(p.results)) + `</p>
+            <p>Vulnerable: ` + fmt.Sprintf("%d", vulnerable) + `</p>
             <p>Generated: ` + time.Now().Format("2006-01-02 15:04:05") + `</p>
         </div>
         <h2>Results</h2>
 `
 
-	if _, err := file.WriteString(html); err != nil {
+	if _, err := file.WriteString(header); err != nil {
 		return fmt.Errorf("failed to write HTML header: %w", err)
 	}
 
@@ -235,13 +328,23 @@ func (p *HTMLReportPlugin) Export(filename string) error {
 			continue
 		}
 
+		class := "info"
+		switch result.Status {
+		case api.StatusVulnerable:
+			class = "success"
+		case api.StatusError, api.StatusTimeout:
+			class = "failure"
+		}
+
 		resultHTML := fmt.Sprintf(`
-        <div class="result info">
-            <h3>Result #%d</h3>
-            <p class="timestamp">Generated: %s</p>
+        <div class="result %s">
+            <h3>Result #%d: %s</h3>
+            <p>%s &mdash; %s</p>
+            <p class="timestamp">Started: %s &mdash; Duration: %d ms</p>
             <pre>%s</pre>
         </div>
-`, i+1, time.Now().Format("2006-01-02 15:04:05"), string(data))
+`, class, i+1, html.EscapeString(string(result.Status)), html.EscapeString(result.Target), html.EscapeString(result.POC),
+			result.StartedAt.Format("2006-01-02 15:04:05"), result.DurationMS, html.EscapeString(string(data)))
 
 		if _, err := file.WriteString(resultHTML); err != nil {
 			return fmt.Errorf("failed to write result: %w", err)
@@ -284,7 +387,15 @@ func (p *ConsoleOutputPlugin) Stop() error {
 }
 
 func (p *ConsoleOutputPlugin) Handle(output interface{}) error {
-	data, err := json.MarshalIndent(output, "", "  ")
+	result, err := asResult(output)
+	if err != nil {
+		return err
+	}
+	return p.AddResult(result)
+}
+
+func (p *ConsoleOutputPlugin) AddResult(result *api.Result) error {
+	data, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal output: %w", err)
 	}
@@ -293,11 +404,7 @@ func (p *ConsoleOutputPlugin) Handle(output interface{}) error {
 	return nil
 }
 
-func (p *ConsoleOutputPlugin) AddResult(result interface{}) error {
-	return p.Handle(result)
-}
-
-func (p *ConsoleOutputPlugin) GetResults() []interface{} {
+func (p *ConsoleOutputPlugin) GetResults() []*api.Result {
 	return nil
 }
 
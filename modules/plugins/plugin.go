@@ -2,6 +2,8 @@ package plugins
 
 import (
 	"fmt"
+
+	"github.com/seaung/pocsuite-go/api"
 )
 
 type PluginCategory string
@@ -84,13 +86,25 @@ type POCPlugin interface {
 	GetPOCs() []string
 }
 
+// ResultPlugin records the results of POC runs. Its Handle method takes a
+// *api.Result as well.
 type ResultPlugin interface {
 	Plugin
-	AddResult(result interface{}) error
-	GetResults() []interface{}
+	AddResult(result *api.Result) error
+	GetResults() []*api.Result
 	Export(filename string) error
 }
 
+// asResult returns the output handed to a result plugin as the result it
+// must be.
+func asResult(output interface{}) (*api.Result, error) {
+	result, ok := output.(*api.Result)
+	if !ok {
+		return nil, fmt.Errorf("unsupported result type: %T", output)
+	}
+	return result, nil
+}
+
 type PluginManager struct {
 	targetPlugins map[string]TargetPlugin
 	pocPlugins    map[string]POCPlugin
@@ -227,9 +241,9 @@ func (pm *PluginManager) StopAll() error {
 	return nil
 }
 
-func (pm *PluginManager) HandleAll(output interface{}) error {
+func (pm *PluginManager) HandleAll(result *api.Result) error {
 	for _, p := range pm.resultPlugins {
-		if err := p.Handle(output); err != nil {
+		if err := p.AddResult(result); err != nil {
 			return fmt.Errorf("failed to handle output with plugin %s: %w", p.GetName(), err)
 		}
 	}
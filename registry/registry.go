@@ -1,6 +1,7 @@
 package registry
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -100,31 +101,48 @@ func ListAll() []string {
 	return names
 }
 
+// pocInfo implements the metadata getters of api.POCBase on top of an
+// api.POCInfo.
+type pocInfo struct {
+	info *api.POCInfo
+}
+
 type YAMLPOCWrapper struct {
+	pocInfo
 	yamlPOC *yamlpoc.YAMLPOC
-	info    *api.POCInfo
 }
 
 func NewYAMLPOCWrapper(yamlPOC *yamlpoc.YAMLPOC) *YAMLPOCWrapper {
-	info := &api.POCInfo{
-		Name:    yamlPOC.Info.Name,
-		Author:  yamlPOC.Info.Author,
-		VulType: yamlPOC.Info.Severity,
-		Desc:    yamlPOC.Info.Description,
+	return &YAMLPOCWrapper{
+		pocInfo: pocInfo{info: newPOCInfo(yamlPOC.ID, yamlPOC.Info)},
+		yamlPOC: yamlPOC,
 	}
+}
 
-	if len(yamlPOC.Info.Reference) > 0 {
-		info.References = yamlPOC.Info.Reference
+func newPOCInfo(id string, yamlInfo yamlpoc.Info) *api.POCInfo {
+	info := &api.POCInfo{
+		VulID:      id,
+		Name:       yamlInfo.Name,
+		Author:     yamlInfo.Author,
+		References: yamlInfo.Reference,
+		AppName:    yamlInfo.Product,
+		Desc:       yamlInfo.Description,
+		Severity:   yamlInfo.Severity,
+		Tags:       yamlInfo.Tags,
+		Vendor:     yamlInfo.Vendor,
 	}
 
-	if len(yamlPOC.Info.Tags) > 0 {
-		info.Samples = yamlPOC.Info.Tags
+	if c := yamlInfo.Classification; c != nil {
+		info.Classification = api.Classification{
+			CVEID:       c.CVEID,
+			CWEID:       c.CWEID,
+			CVSSScore:   c.CVSSScore,
+			CVSSMetrics: c.CVSSMetrics,
+			CPE:         c.CPE,
+		}
 	}
 
-	return &YAMLPOCWrapper{
-		yamlPOC: yamlPOC,
-		info:    info,
-	}
+	return info
 }
 
 func RegisterYAMLPOC(name string, yamlPOC *yamlpoc.YAMLPOC) error {
@@ -132,91 +150,108 @@ func RegisterYAMLPOC(name string, yamlPOC *yamlpoc.YAMLPOC) error {
 	return Register(name, wrapper)
 }
 
-func (w *YAMLPOCWrapper) GetVulID() string {
-	return w.info.VulID
+func (p *pocInfo) GetInfo() *api.POCInfo {
+	return p.info
 }
 
-func (w *YAMLPOCWrapper) GetVersion() string {
-	return w.info.Version
+func (p *pocInfo) GetVulID() string {
+	return p.info.VulID
 }
 
-func (w *YAMLPOCWrapper) GetAuthor() string {
-	return w.info.Author
+func (p *pocInfo) GetVersion() string {
+	return p.info.Version
 }
 
-func (w *YAMLPOCWrapper) GetVulDate() string {
-	return w.info.VulDate
+func (p *pocInfo) GetAuthor() string {
+	return p.info.Author
 }
 
-func (w *YAMLPOCWrapper) GetCreateDate() string {
-	return w.info.CreateDate
+func (p *pocInfo) GetVulDate() string {
+	return p.info.VulDate
 }
 
-func (w *YAMLPOCWrapper) GetUpdateDate() string {
-	return w.info.UpdateDate
+func (p *pocInfo) GetCreateDate() string {
+	return p.info.CreateDate
 }
 
-func (w *YAMLPOCWrapper) GetReferences() []string {
-	return w.info.References
+func (p *pocInfo) GetUpdateDate() string {
+	return p.info.UpdateDate
 }
 
-func (w *YAMLPOCWrapper) GetName() string {
-	return w.info.Name
+func (p *pocInfo) GetReferences() []string {
+	return p.info.References
 }
 
-func (w *YAMLPOCWrapper) GetAppPowerLink() string {
-	return w.info.AppPowerLink
+func (p *pocInfo) GetName() string {
+	return p.info.Name
 }
 
-func (w *YAMLPOCWrapper) GetAppName() string {
-	return w.info.AppName
+func (p *pocInfo) GetAppPowerLink() string {
+	return p.info.AppPowerLink
 }
 
-func (w *YAMLPOCWrapper) GetAppVersion() string {
-	return w.info.AppVersion
+func (p *pocInfo) GetAppName() string {
+	return p.info.AppName
 }
 
-func (w *YAMLPOCWrapper) GetVulType() string {
-	return w.info.VulType
+func (p *pocInfo) GetAppVersion() string {
+	return p.info.AppVersion
 }
 
-func (w *YAMLPOCWrapper) GetCategory() string {
-	return w.info.Category
+func (p *pocInfo) GetVulType() string {
+	return p.info.VulType
 }
 
-func (w *YAMLPOCWrapper) GetSamples() []string {
-	return w.info.Samples
+func (p *pocInfo) GetCategory() string {
+	return p.info.Category
 }
 
-func (w *YAMLPOCWrapper) GetInstallRequires() []string {
-	return w.info.InstallRequires
+func (p *pocInfo) GetSamples() []string {
+	return p.info.Samples
 }
 
-func (w *YAMLPOCWrapper) GetDesc() string {
-	return w.info.Desc
+func (p *pocInfo) GetInstallRequires() []string {
+	return p.info.InstallRequires
 }
 
-func (w *YAMLPOCWrapper) GetPocDesc() string {
-	return w.info.PocDesc
+func (p *pocInfo) GetDesc() string {
+	return p.info.Desc
+}
+
+func (p *pocInfo) GetPocDesc() string {
+	return p.info.PocDesc
 }
 
 func (w *YAMLPOCWrapper) Verify(target string, options map[string]interface{}) (*api.Output, error) {
+	return w.VerifyContext(context.Background(), target, options)
+}
+
+func (w *YAMLPOCWrapper) Attack(target string, options map[string]interface{}) (*api.Output, error) {
+	return w.AttackContext(context.Background(), target, options)
+}
+
+func (w *YAMLPOCWrapper) Shell(target string, options map[string]interface{}) (*api.Output, error) {
+	return w.ShellContext(context.Background(), target, options)
+}
+
+func (w *YAMLPOCWrapper) VerifyContext(ctx context.Context, target string, options map[string]interface{}) (*api.Output, error) {
 	output := api.NewOutput()
 
-	matched, extractedData, err := w.yamlPOC.Execute(target, options)
+	execution, err := w.yamlPOC.Run(ctx, yamlpoc.ModeVerify, target, options)
 	if err != nil {
 		output.FailOutput(fmt.Sprintf("POC execution failed: %v", err))
 		return output, err
 	}
 
-	if matched {
+	if execution.Matched {
 		result := make(map[string]interface{})
 		result["VerifyInfo"] = map[string]interface{}{
 			"URL":       target,
 			"Matched":   true,
-			"Extracted": extractedData,
+			"Extracted": execution.Extracted,
 		}
 		output.SuccessOutput(result)
+		output.Evidence = execution.Evidence
 	} else {
 		output.FailOutput("target is not vulnerable")
 	}
@@ -224,23 +259,24 @@ func (w *YAMLPOCWrapper) Verify(target string, options map[string]interface{}) (
 	return output, nil
 }
 
-func (w *YAMLPOCWrapper) Attack(target string, options map[string]interface{}) (*api.Output, error) {
+func (w *YAMLPOCWrapper) AttackContext(ctx context.Context, target string, options map[string]interface{}) (*api.Output, error) {
 	output := api.NewOutput()
 
-	matched, extractedData, err := w.yamlPOC.Execute(target, options)
+	execution, err := w.yamlPOC.Run(ctx, yamlpoc.ModeAttack, target, options)
 	if err != nil {
 		output.FailOutput(fmt.Sprintf("POC execution failed: %v", err))
 		return output, err
 	}
 
-	if matched {
+	if execution.Matched {
 		result := make(map[string]interface{})
 		result["AttackInfo"] = map[string]interface{}{
 			"URL":       target,
 			"Matched":   true,
-			"Extracted": extractedData,
+			"Extracted": execution.Extracted,
 		}
 		output.SuccessOutput(result)
+		output.Evidence = execution.Evidence
 	} else {
 		output.FailOutput("attack failed")
 	}
@@ -248,12 +284,47 @@ func (w *YAMLPOCWrapper) Attack(target string, options map[string]interface{}) (
 	return output, nil
 }
 
-func (w *YAMLPOCWrapper) Shell(target string, options map[string]interface{}) (*api.Output, error) {
+// ShellContext runs the template's shell requests. The connect-back address
+// is taken from the lhost and lport options, which the template refers to
+// as {{lhost}} and {{lport}}.
+func (w *YAMLPOCWrapper) ShellContext(ctx context.Context, target string, options map[string]interface{}) (*api.Output, error) {
 	output := api.NewOutput()
-	output.FailOutput("shell mode is not supported for YAML POCs")
-	return output, fmt.Errorf("shell mode is not supported for YAML POCs")
+
+	lhost, _ := options["lhost"].(string)
+	lport, _ := options["lport"].(string)
+	if lhost == "" || lport == "" {
+		err := fmt.Errorf("shell mode requires the lhost and lport options")
+		output.FailOutput(err.Error())
+		return output, err
+	}
+
+	execution, err := w.yamlPOC.Run(ctx, yamlpoc.ModeShell, target, options)
+	if err != nil {
+		output.FailOutput(fmt.Sprintf("POC execution failed: %v", err))
+		return output, err
+	}
+
+	if execution.Matched {
+		result := make(map[string]interface{})
+		result["ShellInfo"] = map[string]interface{}{
+			"URL":       target,
+			"LHost":     lhost,
+			"LPort":     lport,
+			"Extracted": execution.Extracted,
+		}
+		output.SuccessOutput(result)
+		output.Evidence = execution.Evidence
+	} else {
+		output.FailOutput("shell payload was not delivered")
+	}
+
+	return output, nil
 }
 
 func (w *YAMLPOCWrapper) GetOptions() map[string]interface{} {
-	return make(map[string]interface{})
+	options, err := w.yamlPOC.APIOptions()
+	if err != nil {
+		return make(map[string]interface{})
+	}
+	return options
 }
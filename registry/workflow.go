@@ -0,0 +1,333 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/seaung/pocsuite-go/api"
+	"github.com/seaung/pocsuite-go/yamlpoc"
+)
+
+// StepRunner runs one template of a workflow in mode.
+type StepRunner func(ctx context.Context, poc api.POCBase, mode, target string, options map[string]interface{}) (*api.Output, error)
+
+// WorkflowWrapper registers a workflow as a single POC. Its templates are
+// looked up in the registry when it runs.
+type WorkflowWrapper struct {
+	pocInfo
+	workflow *yamlpoc.Workflow
+}
+
+func NewWorkflowWrapper(workflow *yamlpoc.Workflow) *WorkflowWrapper {
+	return &WorkflowWrapper{
+		pocInfo:  pocInfo{info: newPOCInfo(workflow.ID, workflow.Info)},
+		workflow: workflow,
+	}
+}
+
+func RegisterWorkflow(name string, workflow *yamlpoc.Workflow) error {
+	return Register(name, NewWorkflowWrapper(workflow))
+}
+
+func (w *WorkflowWrapper) Verify(target string, options map[string]interface{}) (*api.Output, error) {
+	return w.VerifyContext(context.Background(), target, options)
+}
+
+func (w *WorkflowWrapper) Attack(target string, options map[string]interface{}) (*api.Output, error) {
+	return w.AttackContext(context.Background(), target, options)
+}
+
+func (w *WorkflowWrapper) Shell(target string, options map[string]interface{}) (*api.Output, error) {
+	return w.ShellContext(context.Background(), target, options)
+}
+
+func (w *WorkflowWrapper) VerifyContext(ctx context.Context, target string, options map[string]interface{}) (*api.Output, error) {
+	return w.Run(ctx, yamlpoc.ModeVerify, target, options, RunPOC)
+}
+
+func (w *WorkflowWrapper) AttackContext(ctx context.Context, target string, options map[string]interface{}) (*api.Output, error) {
+	return w.Run(ctx, yamlpoc.ModeAttack, target, options, RunPOC)
+}
+
+func (w *WorkflowWrapper) ShellContext(ctx context.Context, target string, options map[string]interface{}) (*api.Output, error) {
+	return w.Run(ctx, yamlpoc.ModeShell, target, options, RunPOC)
+}
+
+// GetOptions returns no options: each template of the workflow resolves
+// its own when it runs.
+func (w *WorkflowWrapper) GetOptions() map[string]interface{} {
+	return make(map[string]interface{})
+}
+
+// Run executes the workflow against target, running every template through
+// run. Templates that gate subtemplates always run in verify mode, the
+// others in mode. Values a template extracted are passed as options to its
+// subtemplates. The whole chain is reported under "WorkflowInfo", and the
+// workflow succeeds when one of the templates that gate nothing matched.
+// Once ctx is done no further template is started, and ctx's error is
+// returned.
+func (w *WorkflowWrapper) Run(ctx context.Context, mode, target string, options map[string]interface{}, run StepRunner) (*api.Output, error) {
+	switch mode {
+	case yamlpoc.ModeVerify, yamlpoc.ModeAttack, yamlpoc.ModeShell:
+	default:
+		return nil, fmt.Errorf("unsupported mode: %s", mode)
+	}
+
+	output := api.NewOutput()
+
+	state := &workflowRun{matched: []string{}}
+	chain := w.runTemplates(ctx, w.workflow.Workflows, mode, target, options, run, state)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"WorkflowInfo": map[string]interface{}{
+			"URL":      target,
+			"Workflow": w.info.VulID,
+			"Matched":  state.matched,
+			"Chain":    chain,
+		},
+	}
+
+	if len(state.matched) > 0 {
+		output.SuccessOutput(result)
+		output.Evidence = state.evidence
+	} else {
+		output.Data = result
+		output.FailOutput("no template of the workflow matched")
+	}
+
+	return output, nil
+}
+
+// Templates returns the names of the registered templates the workflow may
+// run.
+func (w *WorkflowWrapper) Templates() []string {
+	var names []string
+	w.workflow.Walk(func(path string, t *yamlpoc.WorkflowTemplate) error {
+		resolved, _ := resolveWorkflowTemplate(t)
+		for _, name := range resolved {
+			if !oneOf(name, names) {
+				names = append(names, name)
+			}
+		}
+		return nil
+	})
+	return names
+}
+
+// workflowRun collects the templates of a workflow that matched, and their
+// evidence.
+type workflowRun struct {
+	matched  []string
+	evidence []api.Evidence
+}
+
+func (w *WorkflowWrapper) runTemplates(ctx context.Context, templates []yamlpoc.WorkflowTemplate, mode, target string, options map[string]interface{}, run StepRunner, state *workflowRun) []map[string]interface{} {
+	var steps []map[string]interface{}
+
+	for i := range templates {
+		t := &templates[i]
+
+		names, err := resolveWorkflowTemplate(t)
+		if err != nil {
+			steps = append(steps, map[string]interface{}{
+				"Template": workflowReference(t),
+				"Error":    err.Error(),
+			})
+			continue
+		}
+
+		gate := len(t.Subtemplates) > 0 || len(t.Matchers) > 0
+		stepMode := mode
+		if gate {
+			stepMode = yamlpoc.ModeVerify
+		}
+
+		for _, name := range names {
+			if ctx.Err() != nil {
+				return steps
+			}
+
+			poc, _ := Get(name)
+			step := map[string]interface{}{"Template": name}
+			steps = append(steps, step)
+
+			stepOutput, err := run(ctx, poc, stepMode, target, options)
+			if err != nil {
+				step["Error"] = err.Error()
+				continue
+			}
+
+			step["Matched"] = stepOutput.Success
+			extracted := stepOutput.Extracted()
+			if len(extracted) > 0 {
+				step["Extracted"] = extracted
+			}
+			if !stepOutput.Success {
+				step["Message"] = stepOutput.Message
+				continue
+			}
+
+			if !gate {
+				state.matched = append(state.matched, name)
+				state.evidence = append(state.evidence, stepOutput.Evidence...)
+				continue
+			}
+
+			childOptions := make(map[string]interface{}, len(options)+len(extracted))
+			for k, v := range options {
+				childOptions[k] = v
+			}
+			for k, v := range extracted {
+				childOptions[k] = v
+			}
+
+			children := w.runTemplates(ctx, t.Subtemplates, mode, target, childOptions, run, state)
+			matcherNames, _ := extracted["matcher_names"].([]string)
+			for j := range t.Matchers {
+				if t.Matchers[j].Matches(matcherNames) {
+					children = append(children, w.runTemplates(ctx, t.Matchers[j].Subtemplates, mode, target, childOptions, run, state)...)
+				}
+			}
+			if len(children) > 0 {
+				step["Subtemplates"] = children
+			}
+		}
+	}
+
+	return steps
+}
+
+// resolveWorkflowTemplate returns the names of the registered templates an
+// entry refers to. Workflows are never part of another workflow.
+func resolveWorkflowTemplate(t *yamlpoc.WorkflowTemplate) ([]string, error) {
+	if t.Template != "" {
+		poc, exists := Get(t.Template)
+		if !exists {
+			return nil, fmt.Errorf("template '%s' is not loaded", t.Template)
+		}
+		if _, nested := poc.(*WorkflowWrapper); nested {
+			return nil, fmt.Errorf("template '%s' is a workflow, workflows cannot be nested", t.Template)
+		}
+		return []string{t.Template}, nil
+	}
+
+	var names []string
+	for name, poc := range GetAll() {
+		if _, nested := poc.(*WorkflowWrapper); nested {
+			continue
+		}
+		provider, ok := poc.(api.InfoProvider)
+		if !ok {
+			continue
+		}
+		if hasTag(provider.GetInfo().Tags, t.Tags) {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no loaded template has the tags %s", strings.Join(t.Tags, ", "))
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func workflowReference(t *yamlpoc.WorkflowTemplate) string {
+	if t.Template != "" {
+		return t.Template
+	}
+	return "tags:" + strings.Join(t.Tags, ",")
+}
+
+func oneOf(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTag(tags, wanted []string) bool {
+	for _, tag := range tags {
+		for _, w := range wanted {
+			if strings.EqualFold(tag, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RunPOC runs poc in mode, with its declared options resolved against
+// options.
+func RunPOC(ctx context.Context, poc api.POCBase, mode, target string, options map[string]interface{}) (*api.Output, error) {
+	options, err := api.ResolveOptions(poc.GetOptions(), options)
+	if err != nil {
+		return nil, err
+	}
+
+	return Call(ctx, poc, mode, target, options)
+}
+
+// Call runs poc in mode with options as they are. POCs that implement
+// api.ContextPOC are cancelled through ctx; the others are left running in
+// the background once ctx is done, and Call returns ctx's error right away.
+func Call(ctx context.Context, poc api.POCBase, mode, target string, options map[string]interface{}) (*api.Output, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if cp, ok := poc.(api.ContextPOC); ok {
+		switch mode {
+		case yamlpoc.ModeVerify:
+			return cp.VerifyContext(ctx, target, options)
+		case yamlpoc.ModeAttack:
+			return cp.AttackContext(ctx, target, options)
+		case yamlpoc.ModeShell:
+			return cp.ShellContext(ctx, target, options)
+		default:
+			return nil, fmt.Errorf("unsupported mode: %s", mode)
+		}
+	}
+
+	var run func(string, map[string]interface{}) (*api.Output, error)
+	switch mode {
+	case yamlpoc.ModeVerify:
+		run = poc.Verify
+	case yamlpoc.ModeAttack:
+		run = poc.Attack
+	case yamlpoc.ModeShell:
+		run = poc.Shell
+	default:
+		return nil, fmt.Errorf("unsupported mode: %s", mode)
+	}
+
+	type result struct {
+		output *api.Output
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("POC panicked: %v", r)}
+			}
+		}()
+		output, err := run(target, options)
+		done <- result{output, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.output, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
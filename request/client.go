@@ -12,6 +12,9 @@ import (
 	"time"
 )
 
+// DefaultMaxRedirects matches the limit net/http applies by default.
+const DefaultMaxRedirects = 10
+
 type Client struct {
 	httpClient *http.Client
 	headers    map[string]string
@@ -19,6 +22,7 @@ type Client struct {
 	timeout    time.Duration
 	proxy      string
 	verifySSL  bool
+	limiter    *Limiter
 }
 
 type Response struct {
@@ -27,6 +31,9 @@ type Response struct {
 	Headers    map[string]string
 	Cookies    map[string]string
 	StatusCode int
+	// Duration is the time from sending the request until the whole
+	// response body was read.
+	Duration time.Duration
 }
 
 type Config struct {
@@ -34,6 +41,9 @@ type Config struct {
 	Proxy     string
 	VerifySSL bool
 	UserAgent string
+	// Limiter throttles the client's requests. When nil, the default
+	// limiter is used.
+	Limiter *Limiter
 }
 
 func DefaultConfig() *Config {
@@ -64,7 +74,7 @@ func NewClient(config *Config) *Client {
 
 	return &Client{
 		httpClient: &http.Client{
-			Transport: transport,
+			Transport: LimitTransport(transport, config.Limiter),
 			Timeout:   config.Timeout,
 		},
 		headers:   make(map[string]string),
@@ -72,6 +82,7 @@ func NewClient(config *Config) *Client {
 		timeout:   config.Timeout,
 		proxy:     config.Proxy,
 		verifySSL: config.VerifySSL,
+		limiter:   config.Limiter,
 	}
 }
 
@@ -95,6 +106,36 @@ func (c *Client) SetCookies(cookies map[string]string) {
 	}
 }
 
+// SetCookieJar makes the client keep cookies set by responses and send them
+// with later requests.
+func (c *Client) SetCookieJar(jar http.CookieJar) {
+	c.httpClient.Jar = jar
+}
+
+// SetRedirectPolicy controls redirect handling. With follow set, at most max
+// redirects are followed (DefaultMaxRedirects when max is 0); once the limit
+// is reached, or when follow is false, the redirect response itself is
+// returned.
+func (c *Client) SetRedirectPolicy(follow bool, max int) {
+	if max <= 0 {
+		max = DefaultMaxRedirects
+	}
+
+	c.httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !follow || len(via) > max {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+}
+
+func (c *Client) getLimiter() *Limiter {
+	if c.limiter != nil {
+		return c.limiter
+	}
+	return DefaultLimiter()
+}
+
 func (c *Client) Get(urlStr string) (*Response, error) {
 	return c.Request("GET", urlStr, nil, nil)
 }
@@ -112,6 +153,10 @@ func (c *Client) Delete(urlStr string) (*Response, error) {
 }
 
 func (c *Client) Request(method, urlStr string, data interface{}, headers map[string]string) (*Response, error) {
+	return c.RequestWithContext(context.Background(), method, urlStr, data, headers)
+}
+
+func (c *Client) RequestWithContext(ctx context.Context, method, urlStr string, data interface{}, headers map[string]string) (*Response, error) {
 	var body io.Reader
 
 	if data != nil {
@@ -129,20 +174,29 @@ func (c *Client) Request(method, urlStr string, data interface{}, headers map[st
 		}
 	}
 
-	req, err := http.NewRequest(method, urlStr, body)
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	for k, v := range c.headers {
+	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
 
-	for k, v := range headers {
-		req.Header.Set(k, v)
+	return c.Do(req)
+}
+
+// Do sends a prepared request. Client-level headers and cookies are only
+// added when the request does not already carry them, so callers can send
+// duplicate or non-canonical headers untouched.
+func (c *Client) Do(req *http.Request) (*Response, error) {
+	for k, v := range c.headers {
+		if req.Header.Get(k) == "" {
+			req.Header.Set(k, v)
+		}
 	}
 
-	if len(c.cookies) > 0 {
+	if len(c.cookies) > 0 && req.Header.Get("Cookie") == "" {
 		var cookieStrings []string
 		for k, v := range c.cookies {
 			cookieStrings = append(cookieStrings, fmt.Sprintf("%s=%s", k, v))
@@ -150,81 +204,24 @@ func (c *Client) Request(method, urlStr string, data interface{}, headers map[st
 		req.Header.Set("Cookie", strings.Join(cookieStrings, "; "))
 	}
 
+	start := time.Now()
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	response, err := newResponse(resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	respHeaders := make(map[string]string)
-	for k, v := range resp.Header {
-		respHeaders[k] = strings.Join(v, ", ")
-	}
-
-	respCookies := make(map[string]string)
-	for _, cookie := range resp.Cookies() {
-		respCookies[cookie.Name] = cookie.Value
+		return nil, err
 	}
+	response.Duration = time.Since(start)
 
-	return &Response{
-		Response:   resp,
-		BodyText:   string(bodyBytes),
-		Headers:    respHeaders,
-		Cookies:    respCookies,
-		StatusCode: resp.StatusCode,
-	}, nil
+	return response, nil
 }
 
-func (c *Client) RequestWithContext(ctx context.Context, method, urlStr string, data interface{}, headers map[string]string) (*Response, error) {
-	var body io.Reader
-
-	if data != nil {
-		switch v := data.(type) {
-		case string:
-			body = strings.NewReader(v)
-		case []byte:
-			body = bytes.NewReader(v)
-		case map[string]string:
-			values := url.Values{}
-			for key, val := range v {
-				values.Set(key, val)
-			}
-			body = strings.NewReader(values.Encode())
-		}
-	}
-
-	req, err := http.NewRequestWithContext(ctx, method, urlStr, body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	for k, v := range c.headers {
-		req.Header.Set(k, v)
-	}
-
-	for k, v := range headers {
-		req.Header.Set(k, v)
-	}
-
-	if len(c.cookies) > 0 {
-		var cookieStrings []string
-		for k, v := range c.cookies {
-			cookieStrings = append(cookieStrings, fmt.Sprintf("%s=%s", k, v))
-		}
-		req.Header.Set("Cookie", strings.Join(cookieStrings, "; "))
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
-	}
-	defer resp.Body.Close()
-
+func newResponse(resp *http.Response) (*Response, error) {
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
@@ -0,0 +1,411 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultMaxBackoff caps how long a host is paused after it answered
+	// 429 or 503, whatever its Retry-After says.
+	DefaultMaxBackoff = 60 * time.Second
+
+	initialBackoff = time.Second
+)
+
+// LimiterConfig sets the limits of a Limiter. Zero values mean no limit.
+type LimiterConfig struct {
+	// RequestsPerSecond caps the requests sent to all hosts together.
+	RequestsPerSecond float64
+	// HostRequestsPerSecond caps the requests sent to any one host.
+	HostRequestsPerSecond float64
+	// MaxHostConnections caps the requests in flight to any one host.
+	MaxHostConnections int
+	// Delay is waited before every request, either a fixed number of
+	// seconds ("1.5") or a range picked from at random ("1-3").
+	Delay string
+	// MaxBackoff caps the pause after a 429 or 503 answer,
+	// DefaultMaxBackoff when 0.
+	MaxBackoff time.Duration
+}
+
+// Limiter throttles outbound requests with token buckets, one for all hosts
+// and one per host, and bounds the connections open to each host. A host
+// that answers 429 or 503 is paused for as long as its Retry-After asks, or
+// for an exponentially growing interval when it does not say.
+type Limiter struct {
+	global     *bucket
+	hostRate   float64
+	hostConns  int
+	minDelay   time.Duration
+	maxDelay   time.Duration
+	maxBackoff time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimit
+}
+
+type hostLimit struct {
+	bucket *bucket
+	conns  chan struct{}
+
+	mu           sync.Mutex
+	pausedUntil  time.Time
+	backoffCount int
+}
+
+func NewLimiter(config *LimiterConfig) (*Limiter, error) {
+	if config == nil {
+		config = &LimiterConfig{}
+	}
+
+	if config.RequestsPerSecond < 0 || config.HostRequestsPerSecond < 0 {
+		return nil, fmt.Errorf("request rate must not be negative")
+	}
+	if config.MaxHostConnections < 0 {
+		return nil, fmt.Errorf("max host connections must not be negative")
+	}
+
+	minDelay, maxDelay, err := ParseDelay(config.Delay)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBackoff := config.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	return &Limiter{
+		global:     newBucket(config.RequestsPerSecond),
+		hostRate:   config.HostRequestsPerSecond,
+		hostConns:  config.MaxHostConnections,
+		minDelay:   minDelay,
+		maxDelay:   maxDelay,
+		maxBackoff: maxBackoff,
+		hosts:      make(map[string]*hostLimit),
+	}, nil
+}
+
+// ParseDelay parses a delay of seconds, either fixed ("0.5") or a range
+// ("1-3"). An empty delay is no delay.
+func ParseDelay(delay string) (time.Duration, time.Duration, error) {
+	delay = strings.TrimSpace(delay)
+	if delay == "" {
+		return 0, 0, nil
+	}
+
+	low, high, isRange := strings.Cut(delay, "-")
+	if !isRange {
+		high = low
+	}
+
+	from, err := strconv.ParseFloat(strings.TrimSpace(low), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid delay '%s'", delay)
+	}
+	to, err := strconv.ParseFloat(strings.TrimSpace(high), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid delay '%s'", delay)
+	}
+	if from < 0 || to < from {
+		return 0, 0, fmt.Errorf("invalid delay '%s'", delay)
+	}
+
+	return seconds(from), seconds(to), nil
+}
+
+// Acquire waits until a request to host may be sent, and returns the
+// function that gives its connection back once the response is read. A nil
+// limiter never waits.
+func (l *Limiter) Acquire(ctx context.Context, host string) (func(), error) {
+	if l == nil {
+		return func() {}, ctx.Err()
+	}
+
+	if err := sleep(ctx, l.delay()); err != nil {
+		return nil, err
+	}
+
+	h := l.host(host)
+
+	if h.conns != nil {
+		select {
+		case h.conns <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if h.conns != nil {
+			<-h.conns
+		}
+	}
+
+	if err := l.wait(ctx, h); err != nil {
+		release()
+		return nil, err
+	}
+
+	var once sync.Once
+	return func() { once.Do(release) }, nil
+}
+
+func (l *Limiter) wait(ctx context.Context, h *hostLimit) error {
+	h.mu.Lock()
+	paused := time.Until(h.pausedUntil)
+	h.mu.Unlock()
+
+	if err := sleep(ctx, paused); err != nil {
+		return err
+	}
+	if err := l.global.take(ctx); err != nil {
+		return err
+	}
+	if err := h.bucket.take(ctx); err != nil {
+		// No request goes out, so the global token is not used either.
+		l.global.refund()
+		return err
+	}
+	return nil
+}
+
+// Observe adjusts the limits of host to a response it sent: 429 and 503
+// pause the host, anything else ends a pause streak.
+func (l *Limiter) Observe(host string, resp *http.Response) {
+	h := l.host(host)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		h.backoffCount = 0
+		return
+	}
+
+	pause, ok := retryAfter(resp.Header.Get("Retry-After"))
+	if !ok {
+		pause = initialBackoff << min(h.backoffCount, 16)
+	}
+	h.backoffCount++
+
+	if pause > l.maxBackoff {
+		pause = l.maxBackoff
+	}
+	if until := time.Now().Add(pause); until.After(h.pausedUntil) {
+		h.pausedUntil = until
+	}
+}
+
+func (l *Limiter) host(host string) *hostLimit {
+	host = strings.ToLower(host)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostLimit{bucket: newBucket(l.hostRate)}
+		if l.hostConns > 0 {
+			h.conns = make(chan struct{}, l.hostConns)
+		}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+func (l *Limiter) delay() time.Duration {
+	if l.maxDelay <= l.minDelay {
+		return l.minDelay
+	}
+	return l.minDelay + time.Duration(rand.Int63n(int64(l.maxDelay-l.minDelay)))
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
+
+// bucket is a token bucket holding a single token, so that requests are
+// spread evenly instead of sent in bursts. A nil bucket never waits.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64) *bucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &bucket{rate: rate, tokens: 1}
+}
+
+// reserve takes a token and returns how long to wait until it is due.
+// Tokens go negative while waiters queue up, which keeps them in order.
+func (b *bucket) reserve() time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if !b.last.IsZero() {
+		b.tokens = math.Min(1, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return seconds(-b.tokens / b.rate)
+}
+
+// take waits for a token. A waiter that gives up hands its token back, so
+// that it does not hold up the callers after it.
+func (b *bucket) take(ctx context.Context) error {
+	if err := sleep(ctx, b.reserve()); err != nil {
+		b.refund()
+		return err
+	}
+	return nil
+}
+
+// refund gives back a token taken by reserve.
+func (b *bucket) refund() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(1, b.tokens+1)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var defaultLimiter atomic.Pointer[Limiter]
+
+// SetDefaultLimiter sets the limiter shared by every client that was not
+// given its own. A nil limiter lifts all limits.
+func SetDefaultLimiter(limiter *Limiter) {
+	defaultLimiter.Store(limiter)
+}
+
+// DefaultLimiter returns the shared limiter, nil when requests are not
+// limited.
+func DefaultLimiter() *Limiter {
+	return defaultLimiter.Load()
+}
+
+// LimitTransport wraps base so that its requests wait on limiter, or on the
+// default limiter at the time of the request when limiter is nil. Each
+// redirect counts as a request of its own.
+func LimitTransport(base http.RoundTripper, limiter *Limiter) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &limitedTransport{base: base, limiter: limiter}
+}
+
+type limitedTransport struct {
+	base    http.RoundTripper
+	limiter *Limiter
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limiter := t.limiter
+	if limiter == nil {
+		limiter = DefaultLimiter()
+	}
+	if limiter == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	host := req.URL.Hostname()
+
+	release, err := limiter.Acquire(req.Context(), host)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	limiter.Observe(host, resp)
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+
+	return resp, nil
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach the
+// wrapped transport.
+func (t *limitedTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// releaseBody gives the connection of a response back to the limiter when
+// the response is closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
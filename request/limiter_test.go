@@ -0,0 +1,276 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseDelay(t *testing.T) {
+	tests := []struct {
+		delay    string
+		from, to time.Duration
+		wantErr  bool
+	}{
+		{"", 0, 0, false},
+		{"1.5", 1500 * time.Millisecond, 1500 * time.Millisecond, false},
+		{"1-3", time.Second, 3 * time.Second, false},
+		{" 0.5 - 2 ", 500 * time.Millisecond, 2 * time.Second, false},
+		{"abc", 0, 0, true},
+		{"3-1", 0, 0, true},
+		{"-1", 0, 0, true},
+		{"1-", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		from, to, err := ParseDelay(tt.delay)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDelay(%q) error = %v, wantErr %v", tt.delay, err, tt.wantErr)
+			continue
+		}
+		if from != tt.from || to != tt.to {
+			t.Errorf("ParseDelay(%q) = %v, %v, want %v, %v", tt.delay, from, to, tt.from, tt.to)
+		}
+	}
+}
+
+func TestLimiterDelay(t *testing.T) {
+	limiter, err := NewLimiter(&LimiterConfig{Delay: "0.05-0.1"})
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		if d := limiter.delay(); d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Fatalf("Expected a delay between 50ms and 100ms, got %v", d)
+		}
+	}
+
+	start := time.Now()
+	release, err := limiter.Acquire(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Failed to acquire: %v", err)
+	}
+	release()
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected Acquire to wait for the delay, took %v", elapsed)
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	limiter, err := NewLimiter(&LimiterConfig{RequestsPerSecond: 20})
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+
+	// The first request goes out at once, the next five 50ms apart.
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		release, err := limiter.Acquire(context.Background(), "example.com")
+		if err != nil {
+			t.Fatalf("Failed to acquire: %v", err)
+		}
+		release()
+	}
+
+	if elapsed := time.Since(start); elapsed < 240*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected 6 requests at 20/s to take about 250ms, took %v", elapsed)
+	}
+}
+
+func TestLimiterHostRate(t *testing.T) {
+	limiter, err := NewLimiter(&LimiterConfig{HostRequestsPerSecond: 5})
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+
+	acquire := func(host string) {
+		release, err := limiter.Acquire(context.Background(), host)
+		if err != nil {
+			t.Fatalf("Failed to acquire: %v", err)
+		}
+		release()
+	}
+
+	acquire("a.example.com")
+
+	start := time.Now()
+	acquire("b.example.com")
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Expected another host not to wait, took %v", elapsed)
+	}
+
+	start = time.Now()
+	acquire("A.example.com")
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected the same host to wait about 200ms, took %v", elapsed)
+	}
+}
+
+func TestBucketRefund(t *testing.T) {
+	b := newBucket(1)
+	if d := b.reserve(); d != 0 {
+		t.Fatalf("Expected the first token at once, got %v", d)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.take(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the waiter to give up, got %v", err)
+	}
+
+	// Without the refund, the abandoned token would push this one to 2s.
+	if d := b.reserve(); d > time.Second {
+		t.Errorf("Expected the abandoned token to be refunded, next wait is %v", d)
+	}
+}
+
+func TestLimiterCancelledWaiterRefundsGlobalToken(t *testing.T) {
+	limiter, err := NewLimiter(&LimiterConfig{RequestsPerSecond: 1, HostRequestsPerSecond: 1})
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+
+	release, err := limiter.Acquire(context.Background(), "a.example.com")
+	if err != nil {
+		t.Fatalf("Failed to acquire: %v", err)
+	}
+	release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "a.example.com"); err == nil {
+		t.Fatal("Expected the cancelled waiter to fail")
+	}
+
+	if d := limiter.global.reserve(); d > time.Second {
+		t.Errorf("Expected the global token to be refunded, next wait is %v", d)
+	}
+}
+
+func TestLimiterBackoff(t *testing.T) {
+	limiter, err := NewLimiter(&LimiterConfig{MaxBackoff: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+
+	pause := func() time.Duration {
+		h := limiter.host("example.com")
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return time.Until(h.pausedUntil)
+	}
+	observe := func(status int, retryAfter string) {
+		resp := &http.Response{StatusCode: status, Header: make(http.Header)}
+		if retryAfter != "" {
+			resp.Header.Set("Retry-After", retryAfter)
+		}
+		limiter.Observe("example.com", resp)
+	}
+
+	observe(http.StatusTooManyRequests, "2")
+	if p := pause(); p < 1900*time.Millisecond || p > 2*time.Second {
+		t.Errorf("Expected Retry-After to pause for 2s, got %v", p)
+	}
+
+	observe(http.StatusTooManyRequests, "3600")
+	if p := pause(); p > 5*time.Second || p < 4900*time.Millisecond {
+		t.Errorf("Expected the pause to be capped at 5s, got %v", p)
+	}
+
+	limiter = mustLimiter(t, &LimiterConfig{MaxBackoff: time.Minute})
+	observe(http.StatusServiceUnavailable, "")
+	first := pause()
+	observe(http.StatusServiceUnavailable, "")
+	second := pause()
+	if first < 900*time.Millisecond || first > time.Second || second < 1900*time.Millisecond || second > 2*time.Second {
+		t.Errorf("Expected exponential backoff of 1s then 2s, got %v then %v", first, second)
+	}
+
+	observe(http.StatusOK, "")
+	observe(http.StatusServiceUnavailable, "")
+	if p := pause(); p > 2*time.Second {
+		t.Errorf("Expected a success to end the backoff streak, still paused for %v", p)
+	}
+
+	limiter = mustLimiter(t, nil)
+	observe(http.StatusTooManyRequests, "1")
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a paused host to hold requests back, got %v", err)
+	}
+	if _, err := limiter.Acquire(context.Background(), "other.example.com"); err != nil {
+		t.Errorf("Expected other hosts not to be paused, got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if d, ok := retryAfter("120"); !ok || d != 2*time.Minute {
+		t.Errorf("Expected 120 seconds, got %v %v", d, ok)
+	}
+
+	date := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	if d, ok := retryAfter(date); !ok || d < 8*time.Second || d > 10*time.Second {
+		t.Errorf("Expected an HTTP date 10s ahead, got %v %v", d, ok)
+	}
+
+	for _, value := range []string{"", "-1", "soon"} {
+		if _, ok := retryAfter(value); ok {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestLimitTransportHostConnections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	limiter := mustLimiter(t, &LimiterConfig{MaxHostConnections: 1})
+	client := &http.Client{Transport: LimitTransport(nil, limiter)}
+
+	first, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to send first request: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the second request to wait for the open connection, got %v", err)
+	}
+
+	first.Body.Close()
+
+	second, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the connection to be given back on Body.Close, got %v", err)
+	}
+	second.Body.Close()
+}
+
+func TestNilLimiter(t *testing.T) {
+	var limiter *Limiter
+
+	release, err := limiter.Acquire(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Expected a nil limiter not to wait, got %v", err)
+	}
+	release()
+}
+
+func mustLimiter(t *testing.T, config *LimiterConfig) *Limiter {
+	t.Helper()
+
+	limiter, err := NewLimiter(config)
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+	return limiter
+}
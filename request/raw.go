@@ -0,0 +1,141 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// rawDrainLimit and rawDrainTimeout bound how much of a reply that is
+	// not HTTP is read, and for how long, as such a server may neither
+	// stop sending nor close the connection.
+	rawDrainLimit   = 64 << 10
+	rawDrainTimeout = time.Second
+)
+
+// DoRaw writes data to the host of urlStr exactly as given, without any
+// normalisation by net/http, and parses whatever comes back. It is meant for
+// request smuggling and malformed-header checks.
+func (c *Client) DoRaw(urlStr string, data []byte) (*Response, error) {
+	return c.DoRawContext(context.Background(), urlStr, data)
+}
+
+// DoRawContext is DoRaw, giving up as soon as ctx is done.
+func (c *Client) DoRawContext(ctx context.Context, urlStr string, data []byte) (*Response, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	useTLS := strings.EqualFold(u.Scheme, "https")
+	address := u.Host
+	if u.Port() == "" {
+		if useTLS {
+			address = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			address = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = DefaultConfig().Timeout
+	}
+
+	if limiter := c.getLimiter(); limiter != nil {
+		release, err := limiter.Acquire(ctx, u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	if useTLS {
+		tlsDialer := &tls.Dialer{
+			NetDialer: dialer,
+			Config: &tls.Config{
+				InsecureSkipVerify: !c.verifySSL,
+				ServerName:         u.Hostname(),
+			},
+		}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(timeout)
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("failed to set deadline: %w", err)
+	}
+
+	// Unblock reads and writes when ctx is done.
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	start := time.Now()
+
+	if _, err := conn.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write request: %w", err)
+	}
+
+	var captured bytes.Buffer
+	reader := bufio.NewReader(io.TeeReader(conn, &captured))
+
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("failed to read response: %w", ctx.Err())
+	}
+	if err == nil {
+		defer resp.Body.Close()
+
+		if limiter := c.getLimiter(); limiter != nil {
+			limiter.Observe(u.Hostname(), resp)
+		}
+
+		response, err := newResponse(resp)
+		if err != nil {
+			return nil, err
+		}
+		response.Duration = time.Since(start)
+
+		return response, nil
+	}
+
+	// Not valid HTTP: hand back whatever the server sent so matchers can
+	// still inspect it.
+	drainDeadline := time.Now().Add(rawDrainTimeout)
+	if deadline.Before(drainDeadline) {
+		drainDeadline = deadline
+	}
+	if ctx.Err() == nil && conn.SetReadDeadline(drainDeadline) == nil {
+		io.Copy(io.Discard, io.LimitReader(reader, rawDrainLimit))
+	}
+	if captured.Len() == 0 {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return &Response{
+		BodyText: captured.String(),
+		Headers:  make(map[string]string),
+		Cookies:  make(map[string]string),
+		Duration: time.Since(start),
+	}, nil
+}
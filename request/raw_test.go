@@ -0,0 +1,89 @@
+package request
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveRaw answers every connection with reply, and then keeps it open,
+// sending more unless more is nil.
+func serveRaw(t *testing.T, reply string, more []byte) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				conn.Write([]byte(reply))
+				for more != nil {
+					if _, err := conn.Write(more); err != nil {
+						return
+					}
+				}
+				io.Copy(io.Discard, conn)
+			}(conn)
+		}
+	}()
+
+	return "http://" + listener.Addr().String()
+}
+
+func TestDoRaw(t *testing.T) {
+	url := serveRaw(t, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\nX-Test: yes\r\n\r\nok", nil)
+	client := NewClient(&Config{Timeout: 5 * time.Second, Limiter: mustLimiter(t, &LimiterConfig{})})
+
+	response, err := client.DoRaw(url, []byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("Failed to send raw request: %v", err)
+	}
+	if response.StatusCode != 200 || response.BodyText != "ok" || response.Headers["X-Test"] != "yes" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
+
+func TestDoRawNotHTTP(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		more  []byte
+	}{
+		{"banner on an open connection", "220 ftp.example.com ready\r\n", nil},
+		{"endless stream", "garbage\r\n", bytes.Repeat([]byte("x"), 1024)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := serveRaw(t, tt.reply, tt.more)
+			client := NewClient(&Config{Timeout: 30 * time.Second, Limiter: mustLimiter(t, &LimiterConfig{})})
+
+			start := time.Now()
+			response, err := client.DoRaw(url, []byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+			if err != nil {
+				t.Fatalf("Expected the reply as the body, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > rawDrainTimeout+time.Second {
+				t.Errorf("Expected the drain to stop after %v, took %v", rawDrainTimeout, elapsed)
+			}
+			if !strings.HasPrefix(response.BodyText, tt.reply) {
+				t.Errorf("Expected the body to start with the reply, got %q", response.BodyText[:min(len(response.BodyText), 64)])
+			}
+			if len(response.BodyText) > rawDrainLimit+8<<10 {
+				t.Errorf("Expected at most about %d bytes, got %d", rawDrainLimit, len(response.BodyText))
+			}
+		})
+	}
+}
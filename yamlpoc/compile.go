@@ -0,0 +1,195 @@
+package yamlpoc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+)
+
+// responseTimeVariable holds the response time in the env. Templates refer
+// to it as duration, which durationPatcher rewrites; the name is not meant
+// to be used directly.
+const responseTimeVariable = "__duration"
+
+// expressionOptions compile expressions against the helper functions, so
+// that calls to them are type checked, while every other variable is only
+// known at run time. expr's duration() builtin is disabled in favour of the
+// duration helper.
+var expressionOptions = []expr.Option{
+	expr.Env(newEnv()),
+	expr.AllowUndefinedVariables(),
+	expr.DisableBuiltin("duration"),
+	expr.Patch(durationPatcher{}),
+}
+
+// durationPatcher lets duration be both the helper and the response time:
+// the identifier is pointed at responseTimeVariable, except where it is
+// called. The callee is visited before its call, so the call puts the
+// helper back.
+type durationPatcher struct{}
+
+func (durationPatcher) Visit(node *ast.Node) {
+	switch n := (*node).(type) {
+	case *ast.IdentifierNode:
+		if n.Value == "duration" {
+			ast.Patch(node, &ast.IdentifierNode{Value: responseTimeVariable})
+		}
+	case *ast.CallNode:
+		if callee, ok := n.Callee.(*ast.IdentifierNode); ok && callee.Value == responseTimeVariable {
+			ast.Patch(&n.Callee, &ast.IdentifierNode{Value: "duration"})
+		}
+	}
+}
+
+// compileExpressions compiles every expression of the template up front, so
+// that a broken one fails the load rather than the scan.
+func (poc *YAMLPOC) compileExpressions() error {
+	var err error
+
+	poc.forEachTemplate(func(path, s string, dsl bool) {
+		if err != nil {
+			return
+		}
+
+		exprs := []string{dslExpression(s)}
+		if !dsl {
+			exprs, _ = templateExpressions(s)
+		}
+
+		for _, exprStr := range exprs {
+			if _, compileErr := poc.compileExpression(exprStr); compileErr != nil {
+				err = fmt.Errorf("%s: %w", path, compileErr)
+				return
+			}
+		}
+	})
+
+	return err
+}
+
+// compileExpression returns the compiled form of exprStr, compiling it at
+// most once per template.
+func (poc *YAMLPOC) compileExpression(exprStr string) (*vm.Program, error) {
+	poc.programMu.RLock()
+	program, ok := poc.programCache[exprStr]
+	poc.programMu.RUnlock()
+	if ok {
+		return program, nil
+	}
+
+	program, err := expr.Compile(exprStr, expressionOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", exprStr, err)
+	}
+
+	poc.programMu.Lock()
+	if poc.programCache == nil {
+		poc.programCache = make(map[string]*vm.Program)
+	}
+	poc.programCache[exprStr] = program
+	poc.programMu.Unlock()
+
+	return program, nil
+}
+
+// templateExpressions returns the expressions of the {{ }} placeholders in
+// s, and whether s has a "{{" that is never closed.
+func templateExpressions(s string) ([]string, bool) {
+	var exprs []string
+
+	for rest := s; ; {
+		openIdx := strings.Index(rest, "{{")
+		if openIdx == -1 {
+			return exprs, false
+		}
+		closeIdx := strings.Index(rest[openIdx:], "}}")
+		if closeIdx == -1 {
+			return exprs, true
+		}
+		closeIdx += openIdx
+
+		exprs = append(exprs, strings.TrimSpace(rest[openIdx+2:closeIdx]))
+		rest = rest[closeIdx+2:]
+	}
+}
+
+// forEachTemplate calls fn for every string of the template that is
+// evaluated at run time: the {{ }} templated fields of each request, with
+// dsl false, and the dsl matcher and extractor expressions, with dsl true.
+// path locates the string in the template, as in "requests[0].path".
+func (poc *YAMLPOC) forEachTemplate(fn func(path, s string, dsl bool)) {
+	templated := func(path, s string) {
+		if strings.Contains(s, "{{") {
+			fn(path, s, false)
+		}
+	}
+
+	dsl := func(path string, matchers []Matcher, extractors []Extractor) {
+		for i, m := range matchers {
+			for j, d := range m.DSL {
+				fn(fmt.Sprintf("%s.matchers[%d].dsl[%d]", path, i, j), d, true)
+			}
+		}
+		for i, e := range extractors {
+			for j, d := range e.DSL {
+				fn(fmt.Sprintf("%s.extractors[%d].dsl[%d]", path, i, j), d, true)
+			}
+		}
+	}
+
+	for _, section := range []struct {
+		name     string
+		requests []Request
+	}{
+		{"requests", poc.Requests},
+		{"attack", poc.Attack},
+		{"shell", poc.Shell},
+	} {
+		for i, req := range section.requests {
+			path := fmt.Sprintf("%s[%d]", section.name, i)
+
+			templated(path+".path", req.Path)
+
+			names := make([]string, 0, len(req.Headers))
+			for name := range req.Headers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				templated(path+".headers."+name, req.Headers[name])
+			}
+
+			templated(path+".body", req.Body)
+			for j, raw := range req.Raw {
+				templated(fmt.Sprintf("%s.raw[%d]", path, j), raw)
+			}
+
+			dsl(path, req.Matchers, req.Extractors)
+		}
+	}
+
+	for i, netReq := range poc.Network {
+		path := fmt.Sprintf("network[%d]", i)
+
+		templated(path+".host", netReq.Host)
+		templated(path+".port", netReq.Port)
+		for j, input := range netReq.Inputs {
+			templated(fmt.Sprintf("%s.inputs[%d].data", path, j), input.Data)
+		}
+
+		dsl(path, netReq.Matchers, netReq.Extractors)
+	}
+
+	for i, dnsReq := range poc.DNS {
+		path := fmt.Sprintf("dns[%d]", i)
+
+		templated(path+".name", dnsReq.Name)
+		templated(path+".resolver", dnsReq.Resolver)
+
+		dsl(path, dnsReq.Matchers, dnsReq.Extractors)
+	}
+}
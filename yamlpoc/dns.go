@@ -0,0 +1,259 @@
+package yamlpoc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/seaung/pocsuite-go/request"
+)
+
+const (
+	defaultDNSResolver = "8.8.8.8:53"
+	resolvConfPath     = "/etc/resolv.conf"
+)
+
+// DNSRequest describes a single DNS query. Name defaults to the target's
+// host and Resolver, a host or host:port, to the system resolver.
+type DNSRequest struct {
+	Name              string      `yaml:"name,omitempty"`
+	Type              string      `yaml:"type,omitempty"`
+	Class             string      `yaml:"class,omitempty"`
+	Recursion         *bool       `yaml:"recursion,omitempty"`
+	Resolver          string      `yaml:"resolver,omitempty"`
+	Matchers          []Matcher   `yaml:"matchers,omitempty"`
+	Extractors        []Extractor `yaml:"extractors,omitempty"`
+	MatchersCondition string      `yaml:"matchers-condition,omitempty"`
+}
+
+func (poc *YAMLPOC) executeDNSSteps(target string, sess *session, env map[string]interface{}, extractedData map[string]interface{}) (bool, error) {
+	for i, dnsReq := range poc.DNS {
+		response, err := poc.executeDNSRequest(sess.ctx, target, dnsReq, env)
+		if err != nil {
+			return false, fmt.Errorf("failed to execute dns request %d: %w", i, err)
+		}
+
+		req := &Request{
+			Matchers:          dnsReq.Matchers,
+			Extractors:        dnsReq.Extractors,
+			MatchersCondition: dnsReq.MatchersCondition,
+		}
+
+		for _, part := range []string{"answer", "authority", "additional", "raw", "rcode"} {
+			env[part] = response.parts[part]
+		}
+
+		matched, err := poc.processResponse(i, req, response, env, extractedData)
+		if err != nil {
+			return false, err
+		}
+
+		if !matched {
+			return false, nil
+		}
+		sess.record(response)
+	}
+
+	return true, nil
+}
+
+// executeDNSRequest sends the query and exposes the answer, authority and
+// additional sections, the whole reply ("raw", also the body) and the
+// response code as parts. A refused zone transfer is a valid reply with
+// empty sections rather than an error.
+func (poc *YAMLPOC) executeDNSRequest(ctx context.Context, target string, dnsReq DNSRequest, env map[string]interface{}) (*protocolResponse, error) {
+	msg, resolver, err := poc.buildDNSQuery(target, dnsReq, env)
+	if err != nil {
+		return nil, err
+	}
+
+	host, _, _ := net.SplitHostPort(resolver)
+	release, err := request.DefaultLimiter().Acquire(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var reply *dns.Msg
+	start := time.Now()
+	if msg.Question[0].Qtype == dns.TypeAXFR {
+		reply, err = transferZone(ctx, msg, resolver)
+	} else {
+		client := &dns.Client{Timeout: networkTimeout}
+		reply, _, err = client.ExchangeContext(ctx, msg, resolver)
+		if err == nil && reply.Truncated {
+			client.Net = "tcp"
+			reply, _, err = client.ExchangeContext(ctx, msg, resolver)
+		}
+	}
+	elapsed := time.Since(start)
+	if err != nil {
+		return nil, fmt.Errorf("query to %s failed: %w", resolver, err)
+	}
+
+	raw := reply.String()
+	return &protocolResponse{
+		Response: &request.Response{
+			BodyText: raw,
+			Headers:  make(map[string]string),
+			Cookies:  make(map[string]string),
+			Duration: elapsed,
+		},
+		parts: map[string]string{
+			"answer":     recordsText(reply.Answer),
+			"authority":  recordsText(reply.Ns),
+			"additional": recordsText(reply.Extra),
+			"raw":        raw,
+			"all":        raw,
+			"rcode":      dns.RcodeToString[reply.Rcode],
+		},
+		request: msg.String(),
+	}, nil
+}
+
+func (poc *YAMLPOC) buildDNSQuery(target string, dnsReq DNSRequest, env map[string]interface{}) (*dns.Msg, string, error) {
+	name, _ := splitTarget(target)
+	if dnsReq.Name != "" {
+		evaluated, err := poc.evalStringWithExpressions(dnsReq.Name, env)
+		if err != nil {
+			return nil, "", err
+		}
+		name = evaluated
+	}
+	if name == "" {
+		return nil, "", fmt.Errorf("no query name for target '%s'", target)
+	}
+
+	qtype := dns.TypeA
+	if dnsReq.Type != "" {
+		t, ok := dns.StringToType[strings.ToUpper(dnsReq.Type)]
+		if !ok {
+			return nil, "", fmt.Errorf("unsupported dns type: %s", dnsReq.Type)
+		}
+		qtype = t
+	}
+
+	qclass := uint16(dns.ClassINET)
+	if dnsReq.Class != "" {
+		c, ok := dns.StringToClass[strings.ToUpper(dnsReq.Class)]
+		if !ok {
+			return nil, "", fmt.Errorf("unsupported dns class: %s", dnsReq.Class)
+		}
+		qclass = c
+	}
+
+	msg := new(dns.Msg)
+	msg.Id = dns.Id()
+	msg.RecursionDesired = dnsReq.Recursion == nil || *dnsReq.Recursion
+	msg.Question = []dns.Question{{Name: dns.Fqdn(name), Qtype: qtype, Qclass: qclass}}
+
+	resolver, err := poc.dnsResolver(dnsReq.Resolver, env)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return msg, resolver, nil
+}
+
+// dnsResolver returns host:port of the server to query. The resolver may be
+// written as a host, host:port or URL; port 53 is assumed when missing.
+func (poc *YAMLPOC) dnsResolver(resolver string, env map[string]interface{}) (string, error) {
+	if resolver == "" {
+		return systemResolver(), nil
+	}
+
+	evaluated, err := poc.evalStringWithExpressions(resolver, env)
+	if err != nil {
+		return "", err
+	}
+
+	host, port := evaluated, ""
+	if strings.Contains(evaluated, "://") {
+		u, err := url.Parse(evaluated)
+		if err != nil {
+			return "", fmt.Errorf("invalid resolver '%s': %w", evaluated, err)
+		}
+		host = u.Hostname()
+	} else if h, p, err := net.SplitHostPort(evaluated); err == nil {
+		host, port = h, p
+	}
+	if port == "" {
+		port = "53"
+	}
+	if host == "" {
+		return "", fmt.Errorf("invalid resolver '%s'", evaluated)
+	}
+
+	return net.JoinHostPort(strings.Trim(host, "[]"), port), nil
+}
+
+func systemResolver() string {
+	config, err := dns.ClientConfigFromFile(resolvConfPath)
+	if err != nil || len(config.Servers) == 0 {
+		return defaultDNSResolver
+	}
+	return net.JoinHostPort(config.Servers[0], config.Port)
+}
+
+// transferZone performs an AXFR over TCP and collects every transferred
+// record into the answer section of a single reply. A transfer that fails
+// before any record arrives is reported as REFUSED. The transfer is abandoned
+// when ctx ends.
+func transferZone(ctx context.Context, msg *dns.Msg, resolver string) (*dns.Msg, error) {
+	dialer := &net.Dialer{Timeout: networkTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(networkTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetWriteDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// The transfer sets a fresh read deadline before each message, so ctx's
+	// deadline is enforced by closing the connection once ctx is done,
+	// which also unblocks a read in progress.
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	transfer := &dns.Transfer{Conn: &dns.Conn{Conn: conn}, ReadTimeout: networkTimeout}
+
+	envelopes, err := transfer.In(msg, resolver)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reply := new(dns.Msg)
+	reply.SetReply(msg)
+	for envelope := range envelopes {
+		if envelope.Error != nil && len(reply.Answer) == 0 {
+			reply.Rcode = dns.RcodeRefused
+		}
+		reply.Answer = append(reply.Answer, envelope.RR...)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func recordsText(records []dns.RR) string {
+	lines := make([]string, 0, len(records))
+	for _, rr := range records {
+		lines = append(lines, rr.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,89 @@
+package yamlpoc
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/seaung/pocsuite-go/api"
+)
+
+// maxEvidence caps the request/response pairs kept per execution, which
+// payload templates could otherwise produce by the thousand.
+const maxEvidence = 10
+
+// Execution is what running a template found.
+type Execution struct {
+	Matched   bool
+	Extracted map[string]interface{}
+	// Evidence holds the requests whose responses satisfied the matchers,
+	// with the responses, when the template matched.
+	Evidence []api.Evidence
+}
+
+// record keeps response, which satisfied its matchers, as evidence.
+func (s *session) record(response *protocolResponse) {
+	if len(s.evidence) >= maxEvidence {
+		return
+	}
+	s.evidence = append(s.evidence, api.NewEvidence(response.request, responseText(response)))
+}
+
+// requestText renders the HTTP request a response answers, as sent.
+func requestText(response *protocolResponse) string {
+	if response.Response == nil || response.Response.Response == nil || response.Response.Request == nil {
+		return ""
+	}
+	req := response.Response.Request
+
+	var sb strings.Builder
+	sb.WriteString(req.Method + " " + req.URL.RequestURI() + " HTTP/1.1\r\n")
+	sb.WriteString("Host: " + req.Host + "\r\n")
+
+	keys := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range req.Header[k] {
+			sb.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+	sb.WriteString("\r\n")
+	sb.WriteString(requestBody(req))
+
+	return sb.String()
+}
+
+// requestBody returns the body req was sent with, when it can be read again.
+func requestBody(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, api.MaxEvidenceSize+1))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// responseText renders a response the way the "all" part sees it, or as
+// the bare data received for non-HTTP protocols.
+func responseText(response *protocolResponse) string {
+	if raw, ok := response.parts["raw"]; ok {
+		return raw
+	}
+	if response.Response.Response == nil && response.StatusCode == 0 {
+		return response.BodyText
+	}
+	return statusLine(response) + "\r\n" + headerText(response) + "\r\n" + response.BodyText
+}
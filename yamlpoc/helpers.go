@@ -0,0 +1,202 @@
+package yamlpoc
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const randCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// helperFunctions is the standard library available to dsl matchers, dsl
+// extractors and {{ }} placeholders. len() is provided by expr itself.
+//
+// duration is both a helper and the response time: called, as in
+// duration("5s"), it is helperDuration; used as a variable, as in
+// duration >= 5, it is the round-trip time of the response in seconds,
+// which durationPatcher points at responseTimeVariable.
+var helperFunctions = map[string]interface{}{
+	"md5":           helperMD5,
+	"sha1":          helperSHA1,
+	"sha256":        helperSHA256,
+	"base64":        helperBase64,
+	"base64_decode": helperBase64Decode,
+	"hex_encode":    helperHexEncode,
+	"hex_decode":    helperHexDecode,
+	"url_encode":    helperURLEncode,
+	"url_decode":    helperURLDecode,
+	"to_lower":      helperToLower,
+	"to_upper":      helperToUpper,
+	"contains_all":  helperContainsAll,
+	"contains_any":  helperContainsAny,
+	"regex":         helperRegex,
+	"rand_str":      helperRandStr,
+	"rand_int":      helperRandInt,
+	"unix_time":     helperUnixTime,
+	"duration":      helperDuration,
+}
+
+func newEnv() map[string]interface{} {
+	env := make(map[string]interface{}, len(helperFunctions))
+	for name, fn := range helperFunctions {
+		env[name] = fn
+	}
+	return env
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func helperMD5(v interface{}) string {
+	sum := md5.Sum([]byte(toString(v)))
+	return hex.EncodeToString(sum[:])
+}
+
+func helperSHA1(v interface{}) string {
+	sum := sha1.Sum([]byte(toString(v)))
+	return hex.EncodeToString(sum[:])
+}
+
+func helperSHA256(v interface{}) string {
+	sum := sha256.Sum256([]byte(toString(v)))
+	return hex.EncodeToString(sum[:])
+}
+
+func helperBase64(v interface{}) string {
+	return base64.StdEncoding.EncodeToString([]byte(toString(v)))
+}
+
+func helperBase64Decode(v interface{}) (string, error) {
+	s := toString(v)
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil {
+			return "", fmt.Errorf("base64_decode: %w", err)
+		}
+	}
+	return string(data), nil
+}
+
+func helperHexEncode(v interface{}) string {
+	return hex.EncodeToString([]byte(toString(v)))
+}
+
+func helperHexDecode(v interface{}) (string, error) {
+	data, err := hex.DecodeString(toString(v))
+	if err != nil {
+		return "", fmt.Errorf("hex_decode: %w", err)
+	}
+	return string(data), nil
+}
+
+func helperURLEncode(v interface{}) string {
+	return url.QueryEscape(toString(v))
+}
+
+func helperURLDecode(v interface{}) (string, error) {
+	s, err := url.QueryUnescape(toString(v))
+	if err != nil {
+		return "", fmt.Errorf("url_decode: %w", err)
+	}
+	return s, nil
+}
+
+func helperToLower(v interface{}) string {
+	return strings.ToLower(toString(v))
+}
+
+func helperToUpper(v interface{}) string {
+	return strings.ToUpper(toString(v))
+}
+
+func helperContainsAll(v interface{}, substrs ...string) bool {
+	s := toString(v)
+	for _, substr := range substrs {
+		if !strings.Contains(s, substr) {
+			return false
+		}
+	}
+	return true
+}
+
+func helperContainsAny(v interface{}, substrs ...string) bool {
+	s := toString(v)
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func helperRegex(pattern string, v interface{}) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("regex: %w", err)
+	}
+	return re.MatchString(toString(v)), nil
+}
+
+func helperRandStr(n int) string {
+	if n <= 0 {
+		return ""
+	}
+
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(randCharset)))
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			idx = big.NewInt(int64(i % len(randCharset)))
+		}
+		b[i] = randCharset[idx.Int64()]
+	}
+	return string(b)
+}
+
+// helperRandInt returns a random integer in [min, max].
+func helperRandInt(min, max int) (int, error) {
+	if max < min {
+		return 0, fmt.Errorf("rand_int: max %d is less than min %d", max, min)
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max-min)+1))
+	if err != nil {
+		return 0, fmt.Errorf("rand_int: %w", err)
+	}
+	return min + int(n.Int64()), nil
+}
+
+func helperUnixTime() int64 {
+	return time.Now().Unix()
+}
+
+// helperDuration converts a Go duration string such as "5s" or "1m30s"
+// into seconds, so it can be compared against response timings.
+func helperDuration(s string) (float64, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("duration: %w", err)
+	}
+	return d.Seconds(), nil
+}
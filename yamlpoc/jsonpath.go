@@ -0,0 +1,172 @@
+package yamlpoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type jsonSegmentKind int
+
+const (
+	jsonSegmentKey jsonSegmentKind = iota
+	jsonSegmentIndex
+	jsonSegmentWildcard
+)
+
+type jsonSegment struct {
+	kind  jsonSegmentKind
+	key   string
+	index int
+}
+
+// parseJSONQuery parses a JSONPath/jq style query such as `$.data.items[0].id`,
+// `.users[].name`, `.users[*]["e-mail"]` or `data.*.version` into segments.
+func parseJSONQuery(query string) ([]jsonSegment, error) {
+	q := strings.TrimSpace(query)
+	q = strings.TrimPrefix(q, "$")
+
+	var segments []jsonSegment
+	i := 0
+
+	for i < len(q) {
+		switch q[i] {
+		case '.':
+			i++
+			if i < len(q) && q[i] == '*' {
+				segments = append(segments, jsonSegment{kind: jsonSegmentWildcard})
+				i++
+			}
+		case '[':
+			end := strings.IndexByte(q[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid json query '%s': unterminated '['", query)
+			}
+			inner := strings.TrimSpace(q[i+1 : i+end])
+			i += end + 1
+
+			switch {
+			case inner == "" || inner == "*":
+				segments = append(segments, jsonSegment{kind: jsonSegmentWildcard})
+			case len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, jsonSegment{kind: jsonSegmentKey, key: inner[1 : len(inner)-1]})
+			default:
+				idx, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid json query '%s': bad index '%s'", query, inner)
+				}
+				segments = append(segments, jsonSegment{kind: jsonSegmentIndex, index: idx})
+			}
+		default:
+			end := strings.IndexAny(q[i:], ".[")
+			if end == -1 {
+				end = len(q) - i
+			}
+			key := q[i : i+end]
+			if key == "*" {
+				segments = append(segments, jsonSegment{kind: jsonSegmentWildcard})
+			} else {
+				segments = append(segments, jsonSegment{kind: jsonSegmentKey, key: key})
+			}
+			i += end
+		}
+	}
+
+	return segments, nil
+}
+
+// queryJSON evaluates query against the decoded document and returns every
+// value it selects.
+func queryJSON(document interface{}, query string) ([]interface{}, error) {
+	segments, err := parseJSONQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	current := []interface{}{document}
+
+	for _, segment := range segments {
+		var next []interface{}
+
+		for _, node := range current {
+			switch segment.kind {
+			case jsonSegmentKey:
+				if obj, ok := node.(map[string]interface{}); ok {
+					if value, exists := obj[segment.key]; exists {
+						next = append(next, value)
+					}
+				}
+			case jsonSegmentIndex:
+				if arr, ok := node.([]interface{}); ok {
+					idx := segment.index
+					if idx < 0 {
+						idx += len(arr)
+					}
+					if idx >= 0 && idx < len(arr) {
+						next = append(next, arr[idx])
+					}
+				}
+			case jsonSegmentWildcard:
+				switch v := node.(type) {
+				case []interface{}:
+					next = append(next, v...)
+				case map[string]interface{}:
+					for _, key := range sortedKeys(v) {
+						next = append(next, v[key])
+					}
+				}
+			}
+		}
+
+		current = next
+	}
+
+	return current, nil
+}
+
+func decodeJSON(content string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+
+	return document, nil
+}
+
+// jsonValueString renders a selected JSON value for use in the env: scalars
+// as their plain text, objects and arrays as compact JSON.
+func jsonValueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(v); err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return strings.TrimSuffix(buf.String(), "\n")
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
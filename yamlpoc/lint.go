@@ -0,0 +1,657 @@
+package yamlpoc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+	"github.com/miekg/dns"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a problem Lint found in a template, positioned at the YAML node
+// it concerns.
+type Issue struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", i.Line, i.Column, i.Severity, i.Message)
+}
+
+var (
+	matcherTypes   = []string{"status", "word", "regex", "size", "json", "dsl", "binary", "time"}
+	extractorTypes = []string{"regex", "kval", "json", "xpath", "dsl"}
+	httpParts      = []string{"", "body", "data", "header", "status_line", "duration", "all", "response"}
+	dnsParts       = []string{"answer", "authority", "additional", "raw", "rcode"}
+	conditions     = []string{"", "and", "or"}
+	attackTypes    = []string{"", AttackBatteringRam, "sniper", AttackPitchfork, AttackClusterBomb}
+	methods        = []string{"GET", "POST", "PUT", "DELETE"}
+	severities     = []string{"info", "low", "medium", "high", "critical", "unknown"}
+
+	// builtinVariables are set by the engine or the controller rather than
+	// declared by the template.
+	builtinVariables = []string{
+		"BaseURL", "RootURL", "Hostname", "Host", "Port", "Scheme", "Path", "File", "target",
+		"response", "status_code", "body", "headers", "duration", "data",
+		"answer", "authority", "additional", "raw", "rcode",
+		"lhost", "lport", "oast_domain", "oast_url",
+	}
+
+	yamlErrorLine = regexp.MustCompile(`line (\d+)(?:, column (\d+))?: (.*)`)
+	unmarshaler   = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+)
+
+type linter struct {
+	nodes  map[string]*yaml.Node
+	issues []Issue
+	known  map[string]bool
+	// invalid holds the paths whose value could not be decoded, so that the
+	// checks on the decoded template do not report them a second time.
+	invalid map[string]bool
+}
+
+// LintFile lints the template at path. Wordlist payloads are resolved
+// relative to the template's directory.
+func LintFile(path string) ([]Issue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return Lint(data, filepath.Dir(path)), nil
+}
+
+// Lint checks a template without running it. Unlike Parse it rejects keys
+// that do not belong to the schema, and it checks matcher and extractor
+// types, part names, the syntax of every expression, references to
+// variables the template never defines and the required info fields.
+// Undefined variables are warnings, since they may be set from the command
+// line; everything else is an error.
+func Lint(data []byte, baseDir string) []Issue {
+	l := &linter{nodes: make(map[string]*yaml.Node), invalid: make(map[string]bool)}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		l.yamlError(err)
+		return l.issues
+	}
+	if len(root.Content) == 0 {
+		l.issues = append(l.issues, Issue{Line: 1, Column: 1, Severity: SeverityError, Message: "empty template"})
+		return l.issues
+	}
+
+	doc := root.Content[0]
+	if doc.Kind == yaml.MappingNode && mappingValue(doc, "workflows") != nil {
+		l.lintWorkflow(doc)
+		return l.sorted()
+	}
+
+	l.walk(doc, reflect.TypeOf(YAMLPOC{}), "")
+
+	var poc YAMLPOC
+	if err := doc.Decode(&poc); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			l.yamlError(err)
+			return l.sorted()
+		}
+		// Type errors were reported by walk with their column; the partly
+		// decoded template is still worth checking.
+	}
+
+	l.checkTemplate(&poc, baseDir)
+
+	return l.sorted()
+}
+
+// walk records the node of every path and reports keys that are not part
+// of t, along with values that cannot be decoded into it.
+func (l *linter) walk(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	l.nodes[path] = node
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if reflect.PointerTo(t).Implements(unmarshaler) {
+		l.decodeCheck(node, t, path)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			l.errorf(path, "expected a mapping")
+			l.invalid[path] = true
+			return
+		}
+
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				l.add(key, SeverityError, "unknown field '%s'%s", key.Value, inPath(path))
+				continue
+			}
+			l.walk(value, field.Type, joinPath(path, key.Value))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			l.errorf(path, "expected a list")
+			l.invalid[path] = true
+			return
+		}
+		for i, item := range node.Content {
+			l.walk(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			l.errorf(path, "expected a mapping")
+			l.invalid[path] = true
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			l.walk(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))
+		}
+	case reflect.Interface:
+	default:
+		if node.Kind != yaml.ScalarNode {
+			l.errorf(path, "expected a single value")
+			l.invalid[path] = true
+			return
+		}
+		l.decodeCheck(node, t, path)
+	}
+}
+
+func (l *linter) decodeCheck(node *yaml.Node, t reflect.Type, path string) {
+	if err := node.Decode(reflect.New(t).Interface()); err != nil {
+		message := err.Error()
+		if m := yamlErrorLine.FindStringSubmatch(message); m != nil {
+			message = m[3]
+		}
+		l.errorf(path, "%s", strings.TrimPrefix(message, "yaml: "))
+		l.invalid[path] = true
+	}
+}
+
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// lintWorkflow checks a workflow document. The templates it refers to are
+// only resolved when it is loaded.
+func (l *linter) lintWorkflow(doc *yaml.Node) {
+	l.walk(doc, reflect.TypeOf(Workflow{}), "")
+
+	var workflow Workflow
+	if err := doc.Decode(&workflow); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			l.yamlError(err)
+			return
+		}
+	}
+
+	if workflow.ID == "" {
+		l.warnf("", "missing workflow id, the file name will be used instead")
+	}
+	if workflow.Info.Name == "" {
+		l.errorf("info", "missing required info field 'name'")
+	}
+
+	workflow.check(func(path, message string) {
+		if !l.invalidWithin(path) {
+			l.errorf(path, "%s", message)
+		}
+	})
+}
+
+func (l *linter) checkTemplate(poc *YAMLPOC, baseDir string) {
+	if poc.ID == "" {
+		l.warnf("", "missing template id, the file name will be used instead")
+	}
+
+	for _, field := range []struct{ name, value string }{
+		{"name", poc.Info.Name},
+		{"author", poc.Info.Author},
+		{"severity", poc.Info.Severity},
+	} {
+		if field.value == "" {
+			l.errorf("info", "missing required info field '%s'", field.name)
+		}
+	}
+	if poc.Info.Severity != "" && !oneOf(strings.ToLower(poc.Info.Severity), severities) {
+		l.errorf("info.severity", "unknown severity '%s', expected one of %s", poc.Info.Severity, strings.Join(severities, ", "))
+	}
+
+	if len(poc.Requests) == 0 && len(poc.Network) == 0 && len(poc.DNS) == 0 {
+		l.errorf("", "template has no requests, network or dns section")
+	}
+
+	if _, err := poc.APIOptions(); err != nil {
+		l.errorf("options", "%v", err)
+	}
+
+	l.known = poc.knownVariables()
+
+	for _, section := range []struct {
+		name     string
+		requests []Request
+	}{
+		{"requests", poc.Requests},
+		{"attack", poc.Attack},
+		{"shell", poc.Shell},
+	} {
+		for i, req := range section.requests {
+			l.checkRequest(fmt.Sprintf("%s[%d]", section.name, i), req, baseDir)
+		}
+	}
+
+	for i, netReq := range poc.Network {
+		path := fmt.Sprintf("network[%d]", i)
+		l.checkOneOf(joinPath(path, "matchers-condition"), "condition", netReq.MatchersCondition, conditions)
+		l.checkMatchers(path, netReq.Matchers, httpParts)
+		l.checkExtractors(path, netReq.Extractors, httpParts)
+	}
+
+	for i, dnsReq := range poc.DNS {
+		path := fmt.Sprintf("dns[%d]", i)
+		if _, ok := dns.StringToType[strings.ToUpper(dnsReq.Type)]; dnsReq.Type != "" && !ok {
+			l.errorf(joinPath(path, "type"), "unknown dns type '%s'", dnsReq.Type)
+		}
+		if _, ok := dns.StringToClass[strings.ToUpper(dnsReq.Class)]; dnsReq.Class != "" && !ok {
+			l.errorf(joinPath(path, "class"), "unknown dns class '%s'", dnsReq.Class)
+		}
+		l.checkOneOf(joinPath(path, "matchers-condition"), "condition", dnsReq.MatchersCondition, conditions)
+
+		parts := append(append([]string{}, httpParts...), dnsParts...)
+		l.checkMatchers(path, dnsReq.Matchers, parts)
+		l.checkExtractors(path, dnsReq.Extractors, parts)
+	}
+
+	poc.forEachTemplate(func(path, s string, dsl bool) {
+		if dsl {
+			l.checkExpression(path, dslExpression(s))
+		} else {
+			l.checkTemplateString(path, s)
+		}
+	})
+}
+
+func (l *linter) checkRequest(path string, req Request, baseDir string) {
+	if len(req.Raw) == 0 {
+		if req.Method == "" {
+			l.errorf(path, "missing method")
+		} else {
+			l.checkOneOf(joinPath(path, "method"), "method", strings.ToUpper(req.Method), methods)
+		}
+	}
+
+	l.checkOneOf(joinPath(path, "matchers-condition"), "condition", strings.ToLower(req.MatchersCondition), conditions)
+	l.checkOneOf(joinPath(path, "condition"), "condition", strings.ToLower(req.Condition), conditions)
+	l.checkOneOf(joinPath(path, "attack"), "attack type", strings.ToLower(req.Attack), attackTypes)
+
+	if len(req.Payloads) > 0 {
+		if _, err := resolvePayloads(req.Payloads, baseDir); err != nil {
+			l.errorf(joinPath(path, "payloads"), "%v", err)
+		}
+	}
+
+	l.checkMatchers(path, req.Matchers, httpParts)
+	l.checkExtractors(path, req.Extractors, httpParts)
+}
+
+func (l *linter) checkMatchers(path string, matchers []Matcher, parts []string) {
+	for i, m := range matchers {
+		mp := fmt.Sprintf("%s.matchers[%d]", path, i)
+
+		if m.Type == "" {
+			l.errorf(mp, "missing matcher type")
+			continue
+		}
+		if !oneOf(m.Type, matcherTypes) {
+			l.errorf(joinPath(mp, "type"), "unknown matcher type '%s', expected one of %s", m.Type, strings.Join(matcherTypes, ", "))
+			continue
+		}
+		l.checkOneOf(joinPath(mp, "part"), "part", m.Part, parts)
+		l.checkOneOf(joinPath(mp, "condition"), "condition", strings.ToLower(m.Condition), conditions)
+
+		var values int
+		switch m.Type {
+		case "status":
+			values = len(m.Status)
+		case "size":
+			values = len(m.Size)
+		case "word":
+			values = len(m.Words)
+		case "binary":
+			values = len(m.Binary)
+			for j, b := range m.Binary {
+				if _, err := hex.DecodeString(strings.Join(strings.Fields(b), "")); err != nil {
+					l.errorf(fmt.Sprintf("%s.binary[%d]", mp, j), "invalid hex '%s'", b)
+				}
+			}
+		case "regex":
+			values = len(m.patterns())
+			l.checkRegexes(mp, "regex", m.Regex)
+			l.checkRegexes(mp, "regexes", m.Regexes)
+		case "json":
+			values = len(m.JSON)
+			l.checkJSONQueries(mp, m.JSON)
+		case "dsl":
+			values = len(m.DSL)
+		case "time":
+			values = 1
+			if _, err := parseThreshold(m.Threshold); err != nil {
+				l.errorf(mp, "%v", err)
+			}
+		}
+		if values == 0 && !l.invalidWithin(mp) {
+			l.errorf(mp, "%s matcher has nothing to match", m.Type)
+		}
+	}
+}
+
+func (l *linter) checkExtractors(path string, extractors []Extractor, parts []string) {
+	for i, e := range extractors {
+		ep := fmt.Sprintf("%s.extractors[%d]", path, i)
+
+		if e.Type == "" {
+			l.errorf(ep, "missing extractor type")
+			continue
+		}
+		if !oneOf(e.Type, extractorTypes) {
+			l.errorf(joinPath(ep, "type"), "unknown extractor type '%s', expected one of %s", e.Type, strings.Join(extractorTypes, ", "))
+			continue
+		}
+		l.checkOneOf(joinPath(ep, "part"), "part", e.Part, parts)
+
+		switch e.Type {
+		case "regex":
+			l.checkRegexes(ep, "regex", e.Regex)
+			if e.Group != "" {
+				if _, err := strconv.Atoi(e.Group); err != nil && !validGroupName(e.Regex, e.Group) {
+					l.errorf(joinPath(ep, "group"), "no capture group '%s' in the extractor's regexes", e.Group)
+				}
+			}
+		case "json":
+			l.checkJSONQueries(ep, e.JSON)
+		}
+	}
+}
+
+func validGroupName(patterns []string, group string) bool {
+	for _, pattern := range patterns {
+		if re, err := regexp.Compile(pattern); err == nil && re.SubexpIndex(group) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *linter) checkRegexes(path, key string, patterns []string) {
+	for i, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			l.errorf(fmt.Sprintf("%s.%s[%d]", path, key, i), "invalid regex: %v", err)
+		}
+	}
+}
+
+func (l *linter) checkJSONQueries(path string, queries []string) {
+	for i, query := range queries {
+		if _, err := parseJSONQuery(query); err != nil {
+			l.errorf(fmt.Sprintf("%s.json[%d]", path, i), "invalid json query: %v", err)
+		}
+	}
+}
+
+// checkTemplateString checks every {{ }} placeholder of s.
+func (l *linter) checkTemplateString(path, s string) {
+	exprs, unclosed := templateExpressions(s)
+	for _, exprStr := range exprs {
+		l.checkExpression(path, exprStr)
+	}
+	if unclosed {
+		l.warnf(path, "unclosed '{{' is sent as is")
+	}
+}
+
+// checkExpression compiles exprStr the way the engine does and warns about
+// the variables it uses that nothing defines.
+func (l *linter) checkExpression(path, exprStr string) {
+	if _, err := expr.Compile(exprStr, expressionOptions...); err != nil {
+		message, _, _ := strings.Cut(err.Error(), "\n")
+		l.errorf(path, "invalid expression '%s': %s", exprStr, message)
+		return
+	}
+
+	tree, err := parser.Parse(exprStr)
+	if err != nil {
+		return
+	}
+
+	v := &identifierVisitor{declared: make(map[string]bool)}
+	ast.Walk(&tree.Node, v)
+
+	for _, name := range v.identifiers {
+		if !l.known[name] && !v.declared[name] {
+			l.warnf(path, "undefined variable '%s' in expression '%s'", name, exprStr)
+		}
+	}
+}
+
+type identifierVisitor struct {
+	identifiers []string
+	declared    map[string]bool
+}
+
+func (v *identifierVisitor) Visit(node *ast.Node) {
+	switch n := (*node).(type) {
+	case *ast.IdentifierNode:
+		v.identifiers = append(v.identifiers, n.Value)
+	case *ast.VariableDeclaratorNode:
+		v.declared[n.Name] = true
+	}
+}
+
+// knownVariables returns every name an expression of the template may
+// refer to.
+func (poc *YAMLPOC) knownVariables() map[string]bool {
+	known := make(map[string]bool)
+	for name := range helperFunctions {
+		known[name] = true
+	}
+	for _, name := range builtinVariables {
+		known[name] = true
+	}
+	for name := range poc.Variables {
+		known[name] = true
+	}
+	for _, o := range poc.Options {
+		known[o.Name] = true
+	}
+
+	addExtractors := func(extractors []Extractor) {
+		for _, e := range extractors {
+			if e.Name != "" {
+				known[e.Name] = true
+			}
+		}
+	}
+
+	for _, requests := range [][]Request{poc.Requests, poc.Attack, poc.Shell} {
+		for _, req := range requests {
+			for name := range req.Payloads {
+				known[name] = true
+			}
+			if req.Recheck != nil {
+				for name := range req.Recheck.Control {
+					known[name] = true
+				}
+			}
+			addExtractors(req.Extractors)
+		}
+	}
+	for _, netReq := range poc.Network {
+		for _, input := range netReq.Inputs {
+			if input.Name != "" {
+				known[input.Name] = true
+			}
+		}
+		addExtractors(netReq.Extractors)
+	}
+	for _, dnsReq := range poc.DNS {
+		addExtractors(dnsReq.Extractors)
+	}
+
+	return known
+}
+
+func (l *linter) invalidWithin(path string) bool {
+	for p := range l.invalid {
+		if p == path || strings.HasPrefix(p, path+".") || strings.HasPrefix(p, path+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *linter) checkOneOf(path, what, value string, allowed []string) {
+	if !oneOf(value, allowed) {
+		var names []string
+		for _, a := range allowed {
+			if a != "" {
+				names = append(names, a)
+			}
+		}
+		l.errorf(path, "unknown %s '%s', expected one of %s", what, value, strings.Join(names, ", "))
+	}
+}
+
+func oneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *linter) errorf(path, format string, args ...interface{}) {
+	l.add(l.lookup(path), SeverityError, format, args...)
+}
+
+func (l *linter) warnf(path, format string, args ...interface{}) {
+	l.add(l.lookup(path), SeverityWarning, format, args...)
+}
+
+func (l *linter) add(node *yaml.Node, severity, format string, args ...interface{}) {
+	issue := Issue{Line: 1, Column: 1, Severity: severity, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	l.issues = append(l.issues, issue)
+}
+
+// lookup returns the node at path, or at its closest recorded ancestor
+// when the key is absent from the template.
+func (l *linter) lookup(path string) *yaml.Node {
+	for {
+		if node, ok := l.nodes[path]; ok {
+			return node
+		}
+		if path == "" {
+			return nil
+		}
+
+		idx := strings.LastIndexAny(path, ".[")
+		if idx == -1 {
+			path = ""
+		} else {
+			path = path[:idx]
+		}
+	}
+}
+
+func (l *linter) yamlError(err error) {
+	var typeErr *yaml.TypeError
+	messages := []string{err.Error()}
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	for _, message := range messages {
+		issue := Issue{Line: 1, Column: 1, Severity: SeverityError, Message: strings.TrimPrefix(message, "yaml: ")}
+		if m := yamlErrorLine.FindStringSubmatch(message); m != nil {
+			issue.Line, _ = strconv.Atoi(m[1])
+			if m[2] != "" {
+				issue.Column, _ = strconv.Atoi(m[2])
+			}
+			issue.Message = m[3]
+		}
+		l.issues = append(l.issues, issue)
+	}
+}
+
+func (l *linter) sorted() []Issue {
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		if l.issues[i].Line != l.issues[j].Line {
+			return l.issues[i].Line < l.issues[j].Line
+		}
+		return l.issues[i].Column < l.issues[j].Column
+	})
+	return l.issues
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func inPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " in " + path
+}
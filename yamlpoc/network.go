@@ -0,0 +1,260 @@
+package yamlpoc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/seaung/pocsuite-go/request"
+)
+
+const (
+	defaultNetworkReadSize = 1024
+	networkTimeout         = 10 * time.Second
+)
+
+// NetworkRequest describes a raw TCP or TLS conversation with the target.
+type NetworkRequest struct {
+	Host              string         `yaml:"host,omitempty"`
+	Port              string         `yaml:"port,omitempty"`
+	TLS               bool           `yaml:"tls,omitempty"`
+	Inputs            []NetworkInput `yaml:"inputs,omitempty"`
+	ReadSize          int            `yaml:"read-size,omitempty"`
+	Matchers          []Matcher      `yaml:"matchers,omitempty"`
+	Extractors        []Extractor    `yaml:"extractors,omitempty"`
+	MatchersCondition string         `yaml:"matchers-condition,omitempty"`
+}
+
+// NetworkInput is one send step. Data is sent as text, or decoded from hex
+// when Type is "hex"; Read bytes are then read back and, when Name is set,
+// stored in the env under that name.
+type NetworkInput struct {
+	Data string `yaml:"data"`
+	Type string `yaml:"type,omitempty"`
+	Read int    `yaml:"read,omitempty"`
+	Name string `yaml:"name,omitempty"`
+}
+
+func (poc *YAMLPOC) executeNetworkSteps(target string, sess *session, env map[string]interface{}, extractedData map[string]interface{}) (bool, error) {
+	for i, netReq := range poc.Network {
+		address, err := poc.networkAddress(target, netReq, env)
+		if err != nil {
+			return false, fmt.Errorf("failed to resolve address for network request %d: %w", i, err)
+		}
+
+		response, err := poc.executeNetworkRequest(sess.ctx, address, netReq, env)
+		if err != nil {
+			return false, fmt.Errorf("failed to execute network request %d: %w", i, err)
+		}
+
+		req := &Request{
+			Matchers:          netReq.Matchers,
+			Extractors:        netReq.Extractors,
+			MatchersCondition: netReq.MatchersCondition,
+		}
+
+		env["data"] = response.BodyText
+		matched, err := poc.processResponse(i, req, response, env, extractedData)
+		if err != nil {
+			return false, err
+		}
+
+		if !matched {
+			return false, nil
+		}
+		sess.record(response)
+	}
+
+	return true, nil
+}
+
+// networkAddress resolves host:port for a network request. The template's
+// host and port win over the ones taken from the target, which may be a URL
+// or a plain host:port.
+func (poc *YAMLPOC) networkAddress(target string, netReq NetworkRequest, env map[string]interface{}) (string, error) {
+	host, port := splitTarget(target)
+
+	if netReq.Host != "" {
+		evaluated, err := poc.evalStringWithExpressions(netReq.Host, env)
+		if err != nil {
+			return "", err
+		}
+
+		if h, p, err := net.SplitHostPort(evaluated); err == nil {
+			host, port = h, p
+		} else {
+			host = evaluated
+		}
+	}
+
+	if netReq.Port != "" {
+		evaluated, err := poc.evalStringWithExpressions(netReq.Port, env)
+		if err != nil {
+			return "", err
+		}
+		port = evaluated
+	}
+
+	if host == "" || port == "" {
+		return "", fmt.Errorf("no host or port for target '%s'", target)
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
+
+func splitTarget(target string) (string, string) {
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", ""
+		}
+
+		port := u.Port()
+		if port == "" {
+			port = defaultPort(u.Scheme)
+		}
+		return u.Hostname(), port
+	}
+
+	if host, port, err := net.SplitHostPort(target); err == nil {
+		return host, port
+	}
+	return target, ""
+}
+
+// executeNetworkRequest holds the conversation of netReq with address. The
+// data sent is kept as the request of the response. The conversation counts
+// as one request to the shared limiter.
+func (poc *YAMLPOC) executeNetworkRequest(ctx context.Context, address string, netReq NetworkRequest, env map[string]interface{}) (*protocolResponse, error) {
+	host, _, _ := net.SplitHostPort(address)
+
+	release, err := request.DefaultLimiter().Acquire(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	dialer := &net.Dialer{Timeout: networkTimeout}
+
+	var conn net.Conn
+	if netReq.TLS {
+		tlsDialer := &tls.Dialer{
+			NetDialer: dialer,
+			Config: &tls.Config{
+				InsecureSkipVerify: true,
+				ServerName:         host,
+			},
+		}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	// Closing the connection unblocks a read or write in progress. Its
+	// error is then one of a closed connection, so ctx's is returned in
+	// its place.
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	failed := func(err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+
+	var sent, received []byte
+	start := time.Now()
+
+	for _, input := range netReq.Inputs {
+		data, err := poc.evalStringWithExpressions(input.Data, env)
+		if err != nil {
+			return nil, err
+		}
+
+		payload := []byte(data)
+		if strings.EqualFold(input.Type, "hex") {
+			payload, err = hex.DecodeString(strings.Join(strings.Fields(data), ""))
+			if err != nil {
+				return nil, fmt.Errorf("invalid hex input: %w", err)
+			}
+		}
+
+		if err := conn.SetWriteDeadline(time.Now().Add(networkTimeout)); err != nil {
+			return nil, failed(err)
+		}
+		if _, err := conn.Write(payload); err != nil {
+			return nil, failed(fmt.Errorf("failed to write to %s: %w", address, err))
+		}
+		sent = append(sent, payload...)
+
+		if input.Read > 0 {
+			chunk, err := readNetwork(conn, input.Read)
+			if err != nil {
+				return nil, failed(err)
+			}
+			received = append(received, chunk...)
+
+			if input.Name != "" {
+				env[input.Name] = string(chunk)
+			}
+		}
+	}
+
+	readSize := netReq.ReadSize
+	if readSize == 0 && len(received) == 0 {
+		readSize = defaultNetworkReadSize
+	}
+	if readSize > 0 {
+		chunk, err := readNetwork(conn, readSize)
+		if err != nil {
+			return nil, failed(err)
+		}
+		received = append(received, chunk...)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return &protocolResponse{
+		Response: &request.Response{
+			BodyText: string(received),
+			Headers:  make(map[string]string),
+			Cookies:  make(map[string]string),
+			Duration: time.Since(start),
+		},
+		request: string(sent),
+	}, nil
+}
+
+// readNetwork performs a single read of up to size bytes. A peer that closes
+// the connection or stays silent yields whatever was received, possibly
+// nothing, since silence is itself a response worth matching on.
+func readNetwork(conn net.Conn, size int) ([]byte, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(networkTimeout)); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+	n, err := conn.Read(buf)
+	if err != nil && n == 0 && !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil, fmt.Errorf("failed to read: %w", err)
+	}
+
+	return buf[:n], nil
+}
@@ -0,0 +1,780 @@
+package yamlpoc
+
+import (
+	"fmt"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// ConversionError lists every construct of a nuclei template that has no
+// equivalent in the native format. Such templates are rejected as a whole,
+// since running them without those parts could report false positives.
+type ConversionError struct {
+	ID       string
+	Problems []string
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("cannot convert nuclei template '%s': %s", e.ID, strings.Join(e.Problems, "; "))
+}
+
+// nucleiProtocols are the top level keys only nuclei templates have.
+var nucleiProtocols = []string{"http", "tcp", "headless", "code", "file", "ssl", "websocket", "whois", "javascript", "flow", "self-contained"}
+
+// IsNucleiTemplate reports whether data looks like a nuclei template rather
+// than a native one: it uses a nuclei protocol section, lists several paths
+// or hosts per request, or writes its tags or metadata the nuclei way.
+func IsNucleiTemplate(data []byte) bool {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil || len(root.Content) == 0 {
+		return false
+	}
+
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return false
+	}
+
+	for _, key := range nucleiProtocols {
+		if mappingValue(doc, key) != nil {
+			return true
+		}
+	}
+
+	if info := mappingValue(doc, "info"); info != nil {
+		if tags := mappingValue(info, "tags"); tags != nil && tags.Kind == yaml.ScalarNode {
+			return true
+		}
+		if mappingValue(info, "metadata") != nil {
+			return true
+		}
+	}
+
+	for _, section := range []struct{ name, key string }{{"requests", "path"}, {"network", "host"}} {
+		if requests := mappingValue(doc, section.name); requests != nil && requests.Kind == yaml.SequenceNode {
+			for _, req := range requests.Content {
+				if v := mappingValue(req, section.key); v != nil && v.Kind == yaml.SequenceNode {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+type nucleiTemplate struct {
+	ID        string                 `yaml:"id"`
+	Info      nucleiInfo             `yaml:"info"`
+	Variables map[string]interface{} `yaml:"variables"`
+	HTTP      []nucleiHTTP           `yaml:"http"`
+	Requests  []nucleiHTTP           `yaml:"requests"`
+	DNS       []nucleiDNS            `yaml:"dns"`
+	Network   []nucleiNetwork        `yaml:"network"`
+	TCP       []nucleiNetwork        `yaml:"tcp"`
+}
+
+type nucleiInfo struct {
+	Name           string                 `yaml:"name"`
+	Author         StringList             `yaml:"author"`
+	Severity       string                 `yaml:"severity"`
+	Description    string                 `yaml:"description"`
+	Remediation    string                 `yaml:"remediation"`
+	Reference      StringList             `yaml:"reference"`
+	Tags           StringList             `yaml:"tags"`
+	Classification *Classification        `yaml:"classification"`
+	Metadata       map[string]interface{} `yaml:"metadata"`
+}
+
+type nucleiHTTP struct {
+	Method            string                 `yaml:"method"`
+	Path              StringList             `yaml:"path"`
+	Raw               []string               `yaml:"raw"`
+	Headers           map[string]string      `yaml:"headers"`
+	Body              string                 `yaml:"body"`
+	Payloads          map[string]interface{} `yaml:"payloads"`
+	Attack            string                 `yaml:"attack"`
+	StopAtFirstMatch  bool                   `yaml:"stop-at-first-match"`
+	Redirects         bool                   `yaml:"redirects"`
+	HostRedirects     bool                   `yaml:"host-redirects"`
+	MaxRedirects      int                    `yaml:"max-redirects"`
+	Unsafe            bool                   `yaml:"unsafe"`
+	CookieReuse       bool                   `yaml:"cookie-reuse"`
+	DisableCookie     bool                   `yaml:"disable-cookie"`
+	MatchersCondition string                 `yaml:"matchers-condition"`
+	Matchers          []nucleiMatcher        `yaml:"matchers"`
+	Extractors        []nucleiExtractor      `yaml:"extractors"`
+}
+
+type nucleiDNS struct {
+	Name              string            `yaml:"name"`
+	Type              string            `yaml:"type"`
+	Class             string            `yaml:"class"`
+	Recursion         *bool             `yaml:"recursion"`
+	MatchersCondition string            `yaml:"matchers-condition"`
+	Matchers          []nucleiMatcher   `yaml:"matchers"`
+	Extractors        []nucleiExtractor `yaml:"extractors"`
+}
+
+type nucleiNetwork struct {
+	Host              StringList        `yaml:"host"`
+	Port              string            `yaml:"port"`
+	Inputs            []NetworkInput    `yaml:"inputs"`
+	ReadSize          int               `yaml:"read-size"`
+	MatchersCondition string            `yaml:"matchers-condition"`
+	Matchers          []nucleiMatcher   `yaml:"matchers"`
+	Extractors        []nucleiExtractor `yaml:"extractors"`
+}
+
+type nucleiMatcher struct {
+	Type            string   `yaml:"type"`
+	Name            string   `yaml:"name"`
+	Part            string   `yaml:"part"`
+	Condition       string   `yaml:"condition"`
+	Negative        bool     `yaml:"negative"`
+	Words           []string `yaml:"words"`
+	Regex           []string `yaml:"regex"`
+	Status          []int    `yaml:"status"`
+	Size            []int    `yaml:"size"`
+	Binary          []string `yaml:"binary"`
+	DSL             []string `yaml:"dsl"`
+	Encoding        string   `yaml:"encoding"`
+	CaseInsensitive bool     `yaml:"case-insensitive"`
+}
+
+type nucleiExtractor struct {
+	Type            string   `yaml:"type"`
+	Name            string   `yaml:"name"`
+	Part            string   `yaml:"part"`
+	Regex           []string `yaml:"regex"`
+	Group           int      `yaml:"group"`
+	Kval            []string `yaml:"kval"`
+	JSON            []string `yaml:"json"`
+	XPath           []string `yaml:"xpath"`
+	Attribute       string   `yaml:"attribute"`
+	DSL             []string `yaml:"dsl"`
+	Internal        bool     `yaml:"internal"`
+	CaseInsensitive bool     `yaml:"case-insensitive"`
+}
+
+// nucleiFields lists, per construct, the keys the converter understands
+// and the ones that only tune nuclei's engine and are safely dropped. Any
+// other key is reported.
+var nucleiFields = map[string]struct{ supported, ignored []string }{
+	"template": {
+		supported: []string{"id", "info", "variables", "http", "requests", "dns", "network", "tcp"},
+	},
+	"http": {
+		supported: []string{"method", "path", "raw", "headers", "body", "payloads", "attack", "stop-at-first-match",
+			"redirects", "host-redirects", "max-redirects", "unsafe", "cookie-reuse", "disable-cookie",
+			"matchers-condition", "matchers", "extractors"},
+		ignored: []string{"id", "threads", "max-size", "skip-variables-check", "pipeline",
+			"pipeline-concurrent-connections", "pipeline-requests-per-connection", "disable-path-automerge"},
+	},
+	"dns": {
+		supported: []string{"name", "type", "class", "recursion", "matchers-condition", "matchers", "extractors"},
+		ignored:   []string{"id", "retries"},
+	},
+	"network": {
+		supported: []string{"host", "port", "inputs", "read-size", "matchers-condition", "matchers", "extractors"},
+		ignored:   []string{"id", "read-all"},
+	},
+	"input": {
+		supported: []string{"data", "type", "read", "name"},
+	},
+	"matcher": {
+		supported: []string{"type", "name", "part", "condition", "negative", "words", "regex", "status", "size",
+			"binary", "dsl", "encoding", "case-insensitive"},
+		ignored: []string{"internal", "match-all"},
+	},
+	"extractor": {
+		supported: []string{"type", "name", "part", "regex", "group", "kval", "json", "xpath", "attribute", "dsl",
+			"internal", "case-insensitive"},
+	},
+}
+
+// nucleiParts maps nuclei part names to native ones.
+var nucleiParts = map[string]string{
+	"":         "",
+	"body":     "body",
+	"header":   "header",
+	"all":      "all",
+	"response": "all",
+	"raw":      "all",
+	"data":     "data",
+}
+
+// nucleiVariables maps nuclei placeholders to native expressions.
+var nucleiVariables = map[string]string{
+	"interactsh-url": "oast_url",
+	"FQDN":           "Host",
+	"content_length": "len(body)",
+}
+
+// nucleiOnlyVariables have no native equivalent.
+var nucleiOnlyVariables = []string{
+	"header", "all_headers", "raw", "interactsh_protocol", "interactsh_request", "interactsh_response",
+	"randstr",
+}
+
+type nucleiConverter struct {
+	problems []string
+}
+
+func (c *nucleiConverter) problemf(format string, args ...interface{}) {
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+// ParseNucleiFile converts the nuclei template at path and prepares it the
+// way ParseFile prepares a native one.
+func ParseNucleiFile(path string) (*YAMLPOC, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	poc, err := ConvertNuclei(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := poc.loadPayloads(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	if err := poc.compileExpressions(); err != nil {
+		return nil, err
+	}
+
+	return poc, nil
+}
+
+// ConvertNuclei converts a nuclei template to a YAMLPOC. Wordlist payloads
+// are left as paths; ParseNucleiFile resolves them.
+func ConvertNuclei(data []byte) (*YAMLPOC, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if len(root.Content) == 0 {
+		return nil, fmt.Errorf("empty template")
+	}
+
+	var tmpl nucleiTemplate
+	if err := root.Content[0].Decode(&tmpl); err != nil {
+		return nil, fmt.Errorf("failed to parse nuclei template: %w", err)
+	}
+
+	c := &nucleiConverter{}
+	c.checkFields(root.Content[0])
+
+	poc := &YAMLPOC{
+		ID: tmpl.ID,
+		Info: Info{
+			Name:           tmpl.Info.Name,
+			Severity:       tmpl.Info.Severity,
+			Author:         strings.Join(tmpl.Info.Author, ", "),
+			Reference:      tmpl.Info.Reference,
+			Tags:           tmpl.Info.Tags,
+			Description:    tmpl.Info.Description,
+			Remediation:    tmpl.Info.Remediation,
+			Vendor:         toString(tmpl.Info.Metadata["vendor"]),
+			Product:        toString(tmpl.Info.Metadata["product"]),
+			Classification: tmpl.Info.Classification,
+		},
+	}
+
+	if len(tmpl.Variables) > 0 {
+		poc.Variables = make(map[string]string, len(tmpl.Variables))
+		for name, value := range tmpl.Variables {
+			s := toString(value)
+			if strings.Contains(s, "{{") {
+				c.problemf("variable '%s' is an expression, native variables are plain values", name)
+			}
+			poc.Variables[name] = s
+		}
+	}
+
+	for i, req := range append(tmpl.HTTP, tmpl.Requests...) {
+		poc.Requests = append(poc.Requests, c.convertHTTP(fmt.Sprintf("http[%d]", i), req, poc))
+	}
+	for i, dnsReq := range tmpl.DNS {
+		poc.DNS = append(poc.DNS, c.convertDNS(fmt.Sprintf("dns[%d]", i), dnsReq))
+	}
+	for i, netReq := range append(tmpl.Network, tmpl.TCP...) {
+		poc.Network = append(poc.Network, c.convertNetwork(fmt.Sprintf("network[%d]", i), netReq))
+	}
+
+	poc.forEachTemplate(func(path, s string, dsl bool) {
+		exprs := []string{dslExpression(s)}
+		if !dsl {
+			exprs, _ = templateExpressions(s)
+		}
+		for _, exprStr := range exprs {
+			if _, err := poc.compileExpression(exprStr); err != nil {
+				c.problemf("%s: %v", path, err)
+			}
+		}
+	})
+
+	if len(c.problems) > 0 {
+		return nil, &ConversionError{ID: tmpl.ID, Problems: c.problems}
+	}
+
+	return poc, nil
+}
+
+// checkFields reports the keys of the template that the converter would
+// otherwise drop.
+func (c *nucleiConverter) checkFields(doc *yaml.Node) {
+	c.checkKeys(doc, "template", "")
+
+	for _, section := range []struct{ key, kind string }{
+		{"http", "http"}, {"requests", "http"}, {"dns", "dns"}, {"network", "network"}, {"tcp", "network"},
+	} {
+		requests := mappingValue(doc, section.key)
+		if requests == nil || requests.Kind != yaml.SequenceNode {
+			continue
+		}
+
+		for i, req := range requests.Content {
+			path := fmt.Sprintf("%s[%d]", section.key, i)
+			c.checkKeys(req, section.kind, path)
+
+			for _, child := range []struct{ key, kind string }{
+				{"matchers", "matcher"}, {"extractors", "extractor"}, {"inputs", "input"},
+			} {
+				if items := mappingValue(req, child.key); items != nil && items.Kind == yaml.SequenceNode {
+					for j, item := range items.Content {
+						c.checkKeys(item, child.kind, fmt.Sprintf("%s.%s[%d]", path, child.key, j))
+					}
+				}
+			}
+		}
+	}
+}
+
+func (c *nucleiConverter) checkKeys(node *yaml.Node, kind, path string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	fields := nucleiFields[kind]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		if !oneOf(key, fields.supported) && !oneOf(key, fields.ignored) {
+			if path == "" {
+				c.problemf("unsupported section '%s'", key)
+			} else {
+				c.problemf("%s: unsupported field '%s'", path, key)
+			}
+		}
+	}
+}
+
+// convertHTTP converts one http block. A block with several paths, or with a
+// method the native engine has no shortcut for, becomes raw requests, since
+// those too match as soon as one response does.
+func (c *nucleiConverter) convertHTTP(path string, req nucleiHTTP, poc *YAMLPOC) Request {
+	converted := Request{
+		Headers:           make(map[string]string, len(req.Headers)),
+		Body:              c.rewriteTemplate(path+".body", req.Body),
+		Payloads:          req.Payloads,
+		Attack:            req.Attack,
+		StopAtFirstMatch:  req.StopAtFirstMatch,
+		MaxRedirects:      req.MaxRedirects,
+		Unsafe:            req.Unsafe,
+		MatchersCondition: nucleiMatchersCondition(req.MatchersCondition),
+		Matchers:          c.convertMatchers(path, req.Matchers),
+		Extractors:        c.convertExtractors(path, req.Extractors),
+	}
+
+	// Nuclei does not follow redirects unless told to, where the native
+	// engine does.
+	redirects := req.Redirects || req.HostRedirects
+	converted.Redirects = &redirects
+
+	if req.DisableCookie {
+		reuse := false
+		poc.CookieReuse = &reuse
+	} else if req.CookieReuse {
+		reuse := true
+		poc.CookieReuse = &reuse
+	}
+
+	for name, value := range req.Headers {
+		converted.Headers[name] = c.rewriteTemplate(path+".headers."+name, value)
+	}
+	if len(converted.Headers) == 0 {
+		converted.Headers = nil
+	}
+
+	for i, raw := range req.Raw {
+		for _, line := range strings.Split(strings.TrimLeft(raw, " \t\r\n"), "\n") {
+			if strings.HasPrefix(line, "@") {
+				c.problemf("%s.raw[%d]: unsupported annotation '%s'", path, i, strings.TrimSpace(line))
+			}
+		}
+		converted.Raw = append(converted.Raw, c.rewriteTemplate(fmt.Sprintf("%s.raw[%d]", path, i), raw))
+	}
+
+	if len(req.Raw) > 0 {
+		if len(req.Path) > 0 {
+			c.problemf("%s: both path and raw are set", path)
+		}
+		converted.Body = ""
+		return converted
+	}
+
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = "GET"
+	}
+
+	paths := make([]string, 0, len(req.Path))
+	for i, p := range req.Path {
+		paths = append(paths, c.rewriteTemplate(fmt.Sprintf("%s.path[%d]", path, i), p))
+	}
+	if len(paths) == 0 {
+		c.problemf("%s: no path or raw request", path)
+		return converted
+	}
+
+	if len(paths) == 1 && oneOf(method, methods) {
+		converted.Method = method
+		converted.Path = paths[0]
+		return converted
+	}
+
+	names := make([]string, 0, len(converted.Headers))
+	for name := range converted.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, p := range paths {
+		var raw strings.Builder
+		fmt.Fprintf(&raw, "%s %s HTTP/1.1\n", method, p)
+		for _, name := range names {
+			fmt.Fprintf(&raw, "%s: %s\n", name, converted.Headers[name])
+		}
+		raw.WriteString("\n")
+		raw.WriteString(converted.Body)
+		converted.Raw = append(converted.Raw, raw.String())
+	}
+	converted.Headers = nil
+	converted.Body = ""
+
+	return converted
+}
+
+// nucleiMatchersCondition returns condition, or "or" when it is not set:
+// nuclei matches as soon as one matcher does, where the native engine
+// requires all of them by default.
+func nucleiMatchersCondition(condition string) string {
+	if condition == "" {
+		return "or"
+	}
+	return condition
+}
+
+func (c *nucleiConverter) convertDNS(path string, req nucleiDNS) DNSRequest {
+	return DNSRequest{
+		Name:              c.rewriteTemplate(path+".name", req.Name),
+		Type:              req.Type,
+		Class:             req.Class,
+		Recursion:         req.Recursion,
+		MatchersCondition: nucleiMatchersCondition(req.MatchersCondition),
+		Matchers:          c.convertMatchers(path, req.Matchers),
+		Extractors:        c.convertExtractors(path, req.Extractors),
+	}
+}
+
+func (c *nucleiConverter) convertNetwork(path string, req nucleiNetwork) NetworkRequest {
+	converted := NetworkRequest{
+		Port:              req.Port,
+		ReadSize:          req.ReadSize,
+		MatchersCondition: nucleiMatchersCondition(req.MatchersCondition),
+		Matchers:          c.convertMatchers(path, req.Matchers),
+		Extractors:        c.convertExtractors(path, req.Extractors),
+	}
+
+	if strings.Contains(req.Port, ",") {
+		c.problemf("%s: several ports are not supported", path)
+	}
+
+	switch len(req.Host) {
+	case 0:
+	case 1:
+		host := req.Host[0]
+		if strings.HasPrefix(host, "tls://") {
+			converted.TLS = true
+			host = strings.TrimPrefix(host, "tls://")
+		}
+		converted.Host = c.rewriteTemplate(path+".host", host)
+	default:
+		c.problemf("%s: several hosts are not supported", path)
+	}
+
+	for i, input := range req.Inputs {
+		input.Data = c.rewriteTemplate(fmt.Sprintf("%s.inputs[%d].data", path, i), input.Data)
+		converted.Inputs = append(converted.Inputs, input)
+	}
+
+	return converted
+}
+
+func (c *nucleiConverter) convertMatchers(path string, matchers []nucleiMatcher) []Matcher {
+	var converted []Matcher
+
+	for i, m := range matchers {
+		mp := fmt.Sprintf("%s.matchers[%d]", path, i)
+
+		part, ok := nucleiParts[m.Part]
+		if !ok {
+			c.problemf("%s: unsupported part '%s'", mp, m.Part)
+		}
+
+		matcher := Matcher{
+			Type:      m.Type,
+			Name:      m.Name,
+			Condition: m.Condition,
+			Part:      part,
+			Negative:  m.Negative,
+			Status:    m.Status,
+			Size:      m.Size,
+			Binary:    m.Binary,
+		}
+
+		switch m.Type {
+		case "word":
+			switch {
+			case m.Encoding == "hex":
+				matcher.Type = "binary"
+				matcher.Binary = m.Words
+			case m.CaseInsensitive:
+				matcher.Type = "regex"
+				for _, word := range m.Words {
+					matcher.Regex = append(matcher.Regex, "(?i)"+regexp.QuoteMeta(word))
+				}
+			default:
+				matcher.Words = m.Words
+			}
+		case "regex":
+			matcher.Regex = caseInsensitive(m.Regex, m.CaseInsensitive)
+		case "dsl":
+			for j, d := range m.DSL {
+				matcher.DSL = append(matcher.DSL, c.rewriteExpression(fmt.Sprintf("%s.dsl[%d]", mp, j), d))
+			}
+		case "status", "size", "binary":
+		default:
+			c.problemf("%s: unsupported matcher type '%s'", mp, m.Type)
+		}
+
+		converted = append(converted, matcher)
+	}
+
+	return converted
+}
+
+func (c *nucleiConverter) convertExtractors(path string, extractors []nucleiExtractor) []Extractor {
+	var converted []Extractor
+
+	for i, e := range extractors {
+		ep := fmt.Sprintf("%s.extractors[%d]", path, i)
+
+		part, ok := nucleiParts[e.Part]
+		if !ok {
+			c.problemf("%s: unsupported part '%s'", ep, e.Part)
+		}
+
+		extractor := Extractor{
+			Type:      e.Type,
+			Name:      e.Name,
+			Part:      part,
+			JSON:      e.JSON,
+			XPath:     e.XPath,
+			Attribute: e.Attribute,
+			Internal:  e.Internal,
+		}
+
+		switch e.Type {
+		case "regex":
+			extractor.Regex = caseInsensitive(e.Regex, e.CaseInsensitive)
+			if e.Group > 0 {
+				extractor.Group = strconv.Itoa(e.Group)
+			}
+		case "kval":
+			// nuclei names headers in snake case, as in content_type.
+			for _, key := range e.Kval {
+				extractor.Kval = append(extractor.Kval, textproto.CanonicalMIMEHeaderKey(strings.ReplaceAll(key, "_", "-")))
+			}
+		case "dsl":
+			for j, d := range e.DSL {
+				extractor.DSL = append(extractor.DSL, c.rewriteExpression(fmt.Sprintf("%s.dsl[%d]", ep, j), d))
+			}
+		case "json", "xpath":
+		default:
+			c.problemf("%s: unsupported extractor type '%s'", ep, e.Type)
+		}
+
+		converted = append(converted, extractor)
+	}
+
+	return converted
+}
+
+func caseInsensitive(patterns []string, insensitive bool) []string {
+	if !insensitive {
+		return patterns
+	}
+	converted := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		converted = append(converted, "(?i)"+pattern)
+	}
+	return converted
+}
+
+// rewriteTemplate rewrites every {{ }} placeholder of s.
+func (c *nucleiConverter) rewriteTemplate(path, s string) string {
+	var out strings.Builder
+
+	for rest := s; ; {
+		openIdx := strings.Index(rest, "{{")
+		if openIdx == -1 {
+			out.WriteString(rest)
+			break
+		}
+		closeIdx := strings.Index(rest[openIdx:], "}}")
+		if closeIdx == -1 {
+			out.WriteString(rest)
+			break
+		}
+		closeIdx += openIdx
+
+		out.WriteString(rest[:openIdx])
+		out.WriteString("{{")
+		out.WriteString(c.rewriteExpression(path, strings.TrimSpace(rest[openIdx+2:closeIdx])))
+		out.WriteString("}}")
+		rest = rest[closeIdx+2:]
+	}
+
+	return out.String()
+}
+
+// rewriteExpression turns a nuclei DSL expression into an expr one. The
+// syntax is mostly shared; contains() is an operator in expr, a few
+// variables are named differently, and functions or variables nuclei has
+// and the native engine lacks are reported.
+func (c *nucleiConverter) rewriteExpression(path, exprStr string) string {
+	exprStr = strings.TrimSpace(exprStr)
+	if native, ok := nucleiVariables[exprStr]; ok {
+		return native
+	}
+
+	exprStr = rewriteContains(exprStr)
+
+	tree, err := parser.Parse(exprStr)
+	if err != nil {
+		c.problemf("%s: cannot parse expression '%s': %v", path, exprStr, strings.SplitN(err.Error(), "\n", 2)[0])
+		return exprStr
+	}
+
+	v := &nucleiVisitor{converter: c, path: path}
+	ast.Walk(&tree.Node, v)
+	if v.changed {
+		return tree.Node.String()
+	}
+	return exprStr
+}
+
+type nucleiVisitor struct {
+	converter *nucleiConverter
+	path      string
+	changed   bool
+}
+
+func (v *nucleiVisitor) Visit(node *ast.Node) {
+	switch n := (*node).(type) {
+	case *ast.CallNode:
+		if callee, ok := n.Callee.(*ast.IdentifierNode); ok {
+			if _, exists := helperFunctions[callee.Value]; !exists {
+				v.converter.problemf("%s: unsupported function '%s'", v.path, callee.Value)
+			}
+		}
+	case *ast.IdentifierNode:
+		if native, ok := nucleiVariables[n.Value]; ok {
+			replacement, err := parser.Parse(native)
+			if err == nil {
+				*node = replacement.Node
+				v.changed = true
+			}
+			return
+		}
+		if oneOf(n.Value, nucleiOnlyVariables) {
+			v.converter.problemf("%s: unsupported variable '%s'", v.path, n.Value)
+		}
+	}
+}
+
+// rewriteContains turns calls to nuclei's contains() into contains_all(),
+// leaving string literals untouched.
+func rewriteContains(s string) string {
+	const name = "contains"
+
+	var out strings.Builder
+	var quote byte
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+
+		if quote != 0 {
+			out.WriteByte(ch)
+			if ch == '\\' && i+1 < len(s) {
+				i++
+				out.WriteByte(s[i])
+			} else if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case ch == '"' || ch == '\'' || ch == '`':
+			quote = ch
+		case strings.HasPrefix(s[i:], name) && (i == 0 || !isIdentByte(s[i-1])) &&
+			strings.HasPrefix(strings.TrimLeft(s[i+len(name):], " \t"), "("):
+			out.WriteString("contains_all")
+			i += len(name) - 1
+			continue
+		}
+		out.WriteByte(ch)
+	}
+
+	return out.String()
+}
+
+func isIdentByte(ch byte) bool {
+	return ch == '_' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
@@ -0,0 +1,113 @@
+package yamlpoc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/seaung/pocsuite-go/api"
+)
+
+// Option declares a user settable template variable. Enum options list
+// their Choices; dict options map the names a user can pick to the values
+// the template receives.
+type Option struct {
+	Name        string            `yaml:"name"`
+	Type        string            `yaml:"type,omitempty"`
+	Default     string            `yaml:"default,omitempty"`
+	Required    bool              `yaml:"required,omitempty"`
+	Description string            `yaml:"description,omitempty"`
+	Choices     []string          `yaml:"choices,omitempty"`
+	Values      map[string]string `yaml:"values,omitempty"`
+}
+
+// APIOption converts the declaration into its typed api.Option.
+func (o Option) APIOption() (api.Option, error) {
+	switch strings.ToLower(o.Type) {
+	case api.OptionString, "":
+		return api.NewOptString(o.Default, o.Description, o.Required), nil
+	case api.OptionInteger, "integer":
+		value := 0
+		if o.Default != "" {
+			converted, err := (&api.OptInteger{}).Convert(o.Default)
+			if err != nil {
+				return nil, fmt.Errorf("option '%s': invalid default: %w", o.Name, err)
+			}
+			value = converted.(int)
+		}
+		return api.NewOptInteger(value, o.Description, o.Required), nil
+	case api.OptionBool, "boolean":
+		value := false
+		if o.Default != "" {
+			converted, err := (&api.OptBool{}).Convert(o.Default)
+			if err != nil {
+				return nil, fmt.Errorf("option '%s': invalid default: %w", o.Name, err)
+			}
+			value = converted.(bool)
+		}
+		return api.NewOptBool(value, o.Description, o.Required), nil
+	case api.OptionEnum:
+		opt := api.NewOptEnum(o.Default, o.Choices, o.Description, o.Required)
+		if len(o.Choices) == 0 {
+			return nil, fmt.Errorf("option '%s': enum options need choices", o.Name)
+		}
+		if o.Default != "" {
+			if _, err := opt.Convert(o.Default); err != nil {
+				return nil, fmt.Errorf("option '%s': invalid default: %w", o.Name, err)
+			}
+		}
+		return opt, nil
+	case api.OptionDict:
+		opt := api.NewOptDict(o.Default, o.Values, o.Description, o.Required)
+		if len(o.Values) == 0 {
+			return nil, fmt.Errorf("option '%s': dict options need values", o.Name)
+		}
+		if o.Default != "" {
+			if _, err := opt.Convert(o.Default); err != nil {
+				return nil, fmt.Errorf("option '%s': invalid default: %w", o.Name, err)
+			}
+		}
+		return opt, nil
+	default:
+		return nil, fmt.Errorf("option '%s': unsupported type: %s", o.Name, o.Type)
+	}
+}
+
+// APIOptions returns the declared options keyed by name, in the form
+// POCBase.GetOptions reports them.
+func (poc *YAMLPOC) APIOptions() (map[string]interface{}, error) {
+	options := make(map[string]interface{}, len(poc.Options))
+
+	for _, o := range poc.Options {
+		if o.Name == "" {
+			return nil, fmt.Errorf("option without a name")
+		}
+		if _, exists := options[o.Name]; exists {
+			return nil, fmt.Errorf("option '%s' is declared twice", o.Name)
+		}
+
+		opt, err := o.APIOption()
+		if err != nil {
+			return nil, err
+		}
+		options[o.Name] = opt
+	}
+
+	return options, nil
+}
+
+// optionDefaults resolves the declared options missing from variables to
+// their defaults, failing when a required one is missing.
+func (poc *YAMLPOC) optionDefaults(variables map[string]interface{}) (map[string]interface{}, error) {
+	declared, err := poc.APIOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range declared {
+		if _, set := variables[name]; set {
+			delete(declared, name)
+		}
+	}
+
+	return api.ResolveOptions(declared, nil)
+}
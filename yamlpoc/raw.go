@@ -0,0 +1,160 @@
+package yamlpoc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/seaung/pocsuite-go/request"
+)
+
+type rawRequest struct {
+	Method  string
+	Path    string
+	Proto   string
+	Headers [][2]string
+	Body    string
+}
+
+// parseRawRequest parses HTTP/1.1 request text, accepting either CRLF or
+// bare LF line endings in the head. Header order, casing and duplicates are
+// preserved, and the body is kept byte for byte, apart from the bare LF a
+// YAML block adds at its end.
+func parseRawRequest(raw string) (*rawRequest, error) {
+	raw = strings.TrimLeft(raw, " \t\r\n")
+
+	head, body := splitRawRequest(raw)
+	lines := strings.Split(strings.ReplaceAll(head, "\r\n", "\n"), "\n")
+
+	requestLine := strings.Fields(lines[0])
+	if len(requestLine) < 2 {
+		return nil, fmt.Errorf("invalid raw request line: %q", lines[0])
+	}
+
+	parsed := &rawRequest{
+		Method: requestLine[0],
+		Path:   requestLine[1],
+		Proto:  "HTTP/1.1",
+		Body:   body,
+	}
+	if len(requestLine) > 2 {
+		parsed.Proto = requestLine[2]
+	}
+	if !strings.HasSuffix(body, "\r\n") {
+		parsed.Body = strings.TrimSuffix(body, "\n")
+	}
+
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid raw header line: %q", line)
+		}
+		parsed.Headers = append(parsed.Headers, [2]string{strings.TrimSpace(key), strings.TrimSpace(value)})
+	}
+
+	return parsed, nil
+}
+
+// splitRawRequest splits raw at the first blank line, whichever line
+// endings surround it, into the head and the body.
+func splitRawRequest(raw string) (string, string) {
+	end, size := -1, 0
+	for _, sep := range []string{"\r\n\r\n", "\n\r\n", "\n\n"} {
+		if i := strings.Index(raw, sep); i >= 0 && (end < 0 || i < end) {
+			end, size = i, len(sep)
+		}
+	}
+
+	if end < 0 {
+		return raw, ""
+	}
+	return raw[:end], raw[end+size:]
+}
+
+// toHTTPRequest builds a request for the parsed raw text against the
+// target's scheme and host. The Host header of the raw text is replaced by
+// the target's host.
+func (r *rawRequest) toHTTPRequest(target string) (*http.Request, error) {
+	urlStr, err := rawRequestURL(target, r.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if r.Body != "" {
+		body = strings.NewReader(r.Body)
+	}
+
+	req, err := http.NewRequest(r.Method, urlStr, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for _, header := range r.Headers {
+		switch strings.ToLower(header[0]) {
+		case "host", "content-length":
+			continue
+		}
+		req.Header[header[0]] = append(req.Header[header[0]], header[1])
+	}
+
+	return req, nil
+}
+
+func rawRequestURL(target, path string) (string, error) {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path, nil
+	}
+
+	u, err := parseTarget(target)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	return u.Scheme + "://" + u.Host + path, nil
+}
+
+// normalizeUnsafeRaw prepares raw text for verbatim sending. A head written
+// with plain LF line endings, as YAML block scalars are, is converted to
+// CRLF and terminated by an empty line, while the body after it is left as
+// written; text that already contains a CRLF is sent exactly as written.
+func normalizeUnsafeRaw(raw string) string {
+	raw = strings.TrimLeft(raw, " \t\r\n")
+	if strings.Contains(raw, "\r\n") {
+		return raw
+	}
+
+	head, body, _ := strings.Cut(raw, "\n\n")
+	head = strings.ReplaceAll(strings.TrimSuffix(head, "\n"), "\n", "\r\n")
+	return head + "\r\n\r\n" + body
+}
+
+// executeRawRequest sends raw through client. Unsafe requests bypass the
+// client entirely, so they carry neither its cookies nor its redirect policy.
+func (poc *YAMLPOC) executeRawRequest(ctx context.Context, client *request.Client, target, raw string, unsafe bool) (*request.Response, error) {
+	if unsafe {
+		return client.DoRawContext(ctx, target, []byte(normalizeUnsafeRaw(raw)))
+	}
+
+	parsed, err := parseRawRequest(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := parsed.toHTTPRequest(target)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Do(req.WithContext(ctx))
+}
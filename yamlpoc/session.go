@@ -0,0 +1,93 @@
+package yamlpoc
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+
+	"github.com/seaung/pocsuite-go/api"
+	librequest "github.com/seaung/pocsuite-go/lib/request"
+	"github.com/seaung/pocsuite-go/request"
+)
+
+// sharedSessions holds the cookies of templates with shared-session set,
+// keyed by the target's root URL, so that one template can log in and the
+// next ones reuse the session. It serves the runs whose context carries no
+// session manager of its own; see WithSharedSessions.
+var sharedSessions = librequest.NewSessionManager()
+
+type sessionsKey struct{}
+
+// WithSharedSessions returns a copy of ctx with a session manager of its
+// own, so that the shared-session templates run with it share cookies with
+// each other only, and the cookies go once the context is dropped.
+func WithSharedSessions(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionsKey{}, librequest.NewSessionManager())
+}
+
+// sessionManager returns the session manager of ctx, or the process-wide
+// one.
+func sessionManager(ctx context.Context) *librequest.SessionManager {
+	if sessions, ok := ctx.Value(sessionsKey{}).(*librequest.SessionManager); ok {
+		return sessions
+	}
+	return sharedSessions
+}
+
+// session carries the state shared by the HTTP requests of one Execute call.
+type session struct {
+	ctx      context.Context
+	jar      http.CookieJar
+	evidence []api.Evidence
+}
+
+func (poc *YAMLPOC) newSession(ctx context.Context, requests []Request, env map[string]interface{}) *session {
+	if poc.SharedSession {
+		return &session{ctx: ctx, jar: sessionManager(ctx).GetCookieJar(toString(env["RootURL"]))}
+	}
+
+	if poc.cookieReuse(requests) {
+		jar, _ := cookiejar.New(nil)
+		return &session{ctx: ctx, jar: jar}
+	}
+
+	return &session{ctx: ctx}
+}
+
+// cookieReuse reports whether cookies flow between requests. Unless the
+// template says otherwise, they do as soon as it sends more than one request.
+func (poc *YAMLPOC) cookieReuse(requests []Request) bool {
+	if poc.CookieReuse != nil {
+		return *poc.CookieReuse
+	}
+
+	steps := 0
+	for _, req := range requests {
+		if len(req.Raw) > 0 {
+			steps += len(req.Raw)
+		} else {
+			steps++
+		}
+	}
+	return steps > 1
+}
+
+func (s *session) client(req *Request) *request.Client {
+	client := request.NewClient(nil)
+
+	if s.jar != nil {
+		client.SetCookieJar(s.jar)
+	}
+
+	if req.Redirects != nil || req.MaxRedirects > 0 {
+		client.SetRedirectPolicy(req.Redirects == nil || *req.Redirects, req.MaxRedirects)
+	}
+
+	return client
+}
+
+// ClearSharedSessions forgets the cookies of every shared session of the
+// process-wide session manager.
+func ClearSharedSessions() {
+	sharedSessions.ClearAll()
+}
@@ -0,0 +1,106 @@
+package yamlpoc
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// targetVariables exposes the parts of the target to templates. For the
+// target http://example.com:8080/app/index.php they are:
+//
+//	BaseURL   http://example.com:8080/app/index.php
+//	RootURL   http://example.com:8080
+//	Hostname  example.com:8080
+//	Host      example.com
+//	Port      8080
+//	Scheme    http
+//	Path      /app
+//	File      index.php
+//
+// A target without a scheme is treated as http.
+func targetVariables(target string) (map[string]interface{}, error) {
+	u, err := parseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = defaultPort(u.Scheme)
+	}
+
+	dir, file := "", ""
+	if p := u.EscapedPath(); p != "" {
+		if strings.HasSuffix(p, "/") {
+			dir = strings.TrimSuffix(p, "/")
+		} else {
+			idx := strings.LastIndex(p, "/")
+			dir, file = p[:idx], p[idx+1:]
+		}
+	}
+
+	root := u.Scheme + "://" + u.Host
+
+	return map[string]interface{}{
+		"BaseURL":  strings.TrimSuffix(u.String(), "/"),
+		"RootURL":  root,
+		"Hostname": u.Host,
+		"Host":     u.Hostname(),
+		"Port":     port,
+		"Scheme":   u.Scheme,
+		"Path":     dir,
+		"File":     file,
+	}, nil
+}
+
+func parseTarget(target string) (*url.URL, error) {
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target '%s': %w", target, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid target '%s': missing host", target)
+	}
+
+	return u, nil
+}
+
+func defaultPort(scheme string) string {
+	switch strings.ToLower(scheme) {
+	case "https":
+		return "443"
+	case "http":
+		return "80"
+	default:
+		return ""
+	}
+}
+
+// joinURL resolves a request path against the target. Absolute URLs, such
+// as paths written as {{RootURL}}/x, are used as they are; anything else is
+// appended to the target with exactly one slash between them.
+func joinURL(target, path string) (string, error) {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path, nil
+	}
+
+	u, err := parseTarget(target)
+	if err != nil {
+		return "", err
+	}
+
+	base := strings.TrimSuffix(u.String(), "/")
+	switch {
+	case path == "":
+		return base, nil
+	case strings.HasPrefix(path, "?"):
+		return base + path, nil
+	default:
+		return base + "/" + strings.TrimPrefix(path, "/"), nil
+	}
+}
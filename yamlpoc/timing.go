@@ -0,0 +1,102 @@
+package yamlpoc
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/seaung/pocsuite-go/request"
+)
+
+// Recheck confirms a time-based match before it is reported. The request is
+// sent Count more times (once when unset) and every response must satisfy
+// the time matchers again; then a control request, built with the Control
+// variables overriding the env, must not. Control typically sets the delay
+// of the payload to zero.
+type Recheck struct {
+	Count   int               `yaml:"count,omitempty"`
+	Control map[string]string `yaml:"control,omitempty"`
+}
+
+// checkTimeMatcher matches when the response took at least the matcher's
+// threshold, written as a Go duration ("5s") or a number of seconds.
+func (poc *YAMLPOC) checkTimeMatcher(matcher Matcher, response *protocolResponse) (bool, error) {
+	threshold, err := parseThreshold(matcher.Threshold)
+	if err != nil {
+		return false, err
+	}
+
+	return response.Duration >= threshold, nil
+}
+
+func parseThreshold(threshold string) (time.Duration, error) {
+	if threshold == "" {
+		return 0, fmt.Errorf("time matcher requires a threshold")
+	}
+
+	if d, err := time.ParseDuration(threshold); err == nil {
+		return d, nil
+	}
+
+	seconds, err := strconv.ParseFloat(threshold, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid threshold '%s'", threshold)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func (poc *YAMLPOC) recheckTiming(i int, req *Request, send func(env map[string]interface{}) (*request.Response, error), env map[string]interface{}) (bool, error) {
+	var matchers []Matcher
+	for _, matcher := range req.Matchers {
+		if matcher.Type == "time" {
+			matchers = append(matchers, matcher)
+		}
+	}
+	if len(matchers) == 0 {
+		return true, nil
+	}
+
+	count := req.Recheck.Count
+	if count <= 0 {
+		count = 1
+	}
+
+	for n := 0; n < count; n++ {
+		response, err := send(env)
+		if err != nil {
+			return false, err
+		}
+
+		matched, err := poc.checkMatchers(matchers, "and", &protocolResponse{Response: response}, env)
+		if err != nil {
+			return false, fmt.Errorf("failed to recheck request %d: %w", i, err)
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	if len(req.Recheck.Control) == 0 {
+		return true, nil
+	}
+
+	controlEnv := make(map[string]interface{}, len(env)+len(req.Recheck.Control))
+	for k, v := range env {
+		controlEnv[k] = v
+	}
+	for k, v := range req.Recheck.Control {
+		controlEnv[k] = v
+	}
+
+	response, err := send(controlEnv)
+	if err != nil {
+		return false, err
+	}
+
+	delayed, err := poc.checkMatchers(matchers, "and", &protocolResponse{Response: response}, controlEnv)
+	if err != nil {
+		return false, fmt.Errorf("failed to check control request %d: %w", i, err)
+	}
+
+	return !delayed, nil
+}
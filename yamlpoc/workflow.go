@@ -0,0 +1,164 @@
+package yamlpoc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workflow chains templates: each entry runs a template, or every template
+// with one of the given tags, and then the subtemplates of the entry when
+// it matched. Subtemplates listed under a matcher only run when the parent
+// template's named matchers include the matcher's names.
+type Workflow struct {
+	ID        string             `yaml:"id"`
+	Info      Info               `yaml:"info"`
+	Workflows []WorkflowTemplate `yaml:"workflows"`
+}
+
+// WorkflowTemplate refers to a template either by ID, or by a file path
+// relative to the workflow, or to the templates carrying one of Tags.
+type WorkflowTemplate struct {
+	Template     string             `yaml:"template,omitempty"`
+	Tags         StringList         `yaml:"tags,omitempty"`
+	Matchers     []WorkflowMatcher  `yaml:"matchers,omitempty"`
+	Subtemplates []WorkflowTemplate `yaml:"subtemplates,omitempty"`
+}
+
+// WorkflowMatcher runs Subtemplates when the parent template matched with
+// one of Name, or with all of them when Condition is "and".
+type WorkflowMatcher struct {
+	Name         StringList         `yaml:"name"`
+	Condition    string             `yaml:"condition,omitempty"`
+	Subtemplates []WorkflowTemplate `yaml:"subtemplates"`
+}
+
+// IsWorkflow reports whether data is a workflow rather than a template.
+func IsWorkflow(data []byte) bool {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil || len(root.Content) == 0 {
+		return false
+	}
+
+	doc := root.Content[0]
+	return doc.Kind == yaml.MappingNode && mappingValue(doc, "workflows") != nil
+}
+
+func ParseWorkflow(content string) (*Workflow, error) {
+	var workflow Workflow
+
+	if err := yaml.Unmarshal([]byte(content), &workflow); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	if err := workflow.validate(); err != nil {
+		return nil, err
+	}
+
+	return &workflow, nil
+}
+
+func ParseWorkflowFile(path string) (*Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return ParseWorkflow(string(data))
+}
+
+// IsFile reports whether the entry names its template by file path rather
+// than by ID.
+func (t *WorkflowTemplate) IsFile() bool {
+	ext := strings.ToLower(filepath.Ext(t.Template))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// Matches reports whether the matcher names a parent template matched with
+// select the matcher's subtemplates.
+func (m *WorkflowMatcher) Matches(names []string) bool {
+	and := strings.ToLower(m.Condition) == "and"
+
+	for _, name := range m.Name {
+		matched := oneOf(name, names)
+		if matched && !and {
+			return true
+		}
+		if !matched && and {
+			return false
+		}
+	}
+
+	return and && len(m.Name) > 0
+}
+
+// Walk calls fn for every template entry of the workflow, subtemplates
+// included, stopping at the first error. path locates the entry, as in
+// "workflows[0].matchers[1].subtemplates[0]".
+func (w *Workflow) Walk(fn func(path string, t *WorkflowTemplate) error) error {
+	return walkWorkflowTemplates("workflows", w.Workflows, fn)
+}
+
+func walkWorkflowTemplates(path string, templates []WorkflowTemplate, fn func(path string, t *WorkflowTemplate) error) error {
+	for i := range templates {
+		t := &templates[i]
+		tp := fmt.Sprintf("%s[%d]", path, i)
+
+		if err := fn(tp, t); err != nil {
+			return err
+		}
+		if err := walkWorkflowTemplates(tp+".subtemplates", t.Subtemplates, fn); err != nil {
+			return err
+		}
+		for j := range t.Matchers {
+			mp := fmt.Sprintf("%s.matchers[%d]", tp, j)
+			if err := walkWorkflowTemplates(mp+".subtemplates", t.Matchers[j].Subtemplates, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Workflow) validate() error {
+	var err error
+	w.check(func(path, message string) {
+		if err == nil {
+			err = fmt.Errorf("%s: %s", path, message)
+		}
+	})
+	return err
+}
+
+// check reports every structural problem of the workflow to report.
+func (w *Workflow) check(report func(path, message string)) {
+	if len(w.Workflows) == 0 {
+		report("workflows", "workflow has no templates")
+		return
+	}
+
+	w.Walk(func(path string, t *WorkflowTemplate) error {
+		switch {
+		case t.Template == "" && len(t.Tags) == 0:
+			report(path, "entry needs a template or tags")
+		case t.Template != "" && len(t.Tags) > 0:
+			report(path, "entry has both a template and tags")
+		}
+
+		for j, m := range t.Matchers {
+			mp := fmt.Sprintf("%s.matchers[%d]", path, j)
+			if len(m.Name) == 0 {
+				report(mp, "matcher needs a name")
+			}
+			if !oneOf(strings.ToLower(m.Condition), conditions) {
+				report(joinPath(mp, "condition"), fmt.Sprintf("unknown condition '%s', expected and, or", m.Condition))
+			}
+			if len(m.Subtemplates) == 0 {
+				report(mp, "matcher has no subtemplates")
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,100 @@
+package yamlpoc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xmlquery"
+)
+
+func (poc *YAMLPOC) extractXPath(extractor Extractor, response *protocolResponse) (interface{}, error) {
+	content, err := getPartContent(extractor.Part, response)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	if isXMLDocument(content, response.Headers["Content-Type"]) {
+		values, err = queryXMLXPath(content, extractor.XPath, extractor.Attribute)
+		if err != nil {
+			values, err = queryHTMLXPath(content, extractor.XPath, extractor.Attribute)
+		}
+	} else {
+		values, err = queryHTMLXPath(content, extractor.XPath, extractor.Attribute)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return extractedValue(values), nil
+}
+
+// isXMLDocument reports whether content should be parsed as strict XML
+// (SOAP, RSS, ...) rather than as HTML.
+func isXMLDocument(content, contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "html") {
+		return false
+	}
+	if strings.Contains(contentType, "xml") {
+		return true
+	}
+
+	trimmed := strings.TrimSpace(strings.TrimPrefix(content, "\ufeff"))
+	return strings.HasPrefix(trimmed, "<?xml")
+}
+
+func queryHTMLXPath(content string, queries []string, attribute string) ([]string, error) {
+	doc, err := htmlquery.Parse(strings.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	var values []string
+	for _, query := range queries {
+		nodes, err := htmlquery.QueryAll(doc, query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xpath '%s': %w", query, err)
+		}
+
+		for _, node := range nodes {
+			if attribute != "" {
+				if value := htmlquery.SelectAttr(node, attribute); value != "" {
+					values = append(values, value)
+				}
+				continue
+			}
+			values = append(values, strings.TrimSpace(htmlquery.InnerText(node)))
+		}
+	}
+
+	return values, nil
+}
+
+func queryXMLXPath(content string, queries []string, attribute string) ([]string, error) {
+	doc, err := xmlquery.Parse(strings.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XML: %w", err)
+	}
+
+	var values []string
+	for _, query := range queries {
+		nodes, err := xmlquery.QueryAll(doc, query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xpath '%s': %w", query, err)
+		}
+
+		for _, node := range nodes {
+			if attribute != "" {
+				if value := node.SelectAttr(attribute); value != "" {
+					values = append(values, value)
+				}
+				continue
+			}
+			values = append(values, strings.TrimSpace(node.InnerText()))
+		}
+	}
+
+	return values, nil
+}